@@ -4,10 +4,19 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/a8m/tree"
+	"github.com/a8m/tree/gitfs"
+	"github.com/a8m/tree/httpfs"
+	"github.com/a8m/tree/imagefs"
+	"github.com/a8m/tree/listfs"
 	"github.com/a8m/tree/ostree"
+	"github.com/a8m/tree/tarfs"
+	"github.com/a8m/tree/zipfs"
 )
 
 var (
@@ -19,19 +28,41 @@ var (
 	noreport   = flag.Bool("noreport", false, "")
 	l          = flag.Bool("l", false, "")
 	L          = flag.Int("L", 3, "")
-	P          = flag.String("P", "", "")
-	I          = flag.String("I", "", "")
+	P          = listFlag("P")
+	I          = listFlag("I")
+	includes   = listFlag("include-from")
+	excludes   = listFlag("exclude-from")
+	regex      = flag.Bool("regex", false, "")
 	o          = flag.String("o", "", "")
+	prune      = flag.Bool("prune", false, "")
+	gitignore  = flag.Bool("gitignore", false, "")
+	minSize    = flag.String("min-size", "", "")
+	maxSize    = flag.String("max-size", "", "")
+	newer      = flag.String("newer", "", "")
+	older      = flag.String("older", "", "")
+	newerThan  = flag.String("newer-than", "", "")
+	changed    = flag.String("changed-within", "", "")
+	timeField  = flag.String("time", "", "")
+	gitRev     = flag.String("git-rev", "", "")
+	fromfile   = flag.Bool("fromfile", false, "")
+	fromtab    = flag.Bool("fromtabfile", false, "")
+	overlay    = flag.Bool("overlay", false, "")
 	// Files
-	s      = flag.Bool("s", false, "")
-	h      = flag.Bool("h", false, "")
-	p      = flag.Bool("p", false, "")
-	u      = flag.Bool("u", false, "")
-	g      = flag.Bool("g", false, "")
-	Q      = flag.Bool("Q", false, "")
-	D      = flag.Bool("D", false, "")
-	inodes = flag.Bool("inodes", false, "")
-	device = flag.Bool("device", false, "")
+	s         = flag.Bool("s", false, "")
+	h         = flag.Bool("h", false, "")
+	p         = flag.Bool("p", false, "")
+	u         = flag.Bool("u", false, "")
+	g         = flag.Bool("g", false, "")
+	Q         = flag.Bool("Q", false, "")
+	D         = flag.Bool("D", false, "")
+	inodes    = flag.Bool("inodes", false, "")
+	device    = flag.Bool("device", false, "")
+	showLayer = flag.Bool("show-layer", false, "")
+	// Archives
+	compressed = flag.Bool("compressed", false, "")
+	// Images
+	image = flag.Bool("image", false, "")
+	layer = flag.Int("layer", -1, "")
 	// Sort
 	U         = flag.Bool("U", false, "")
 	v         = flag.Bool("v", false, "")
@@ -43,10 +74,25 @@ var (
 	// Graphics
 	i = flag.Bool("i", false, "")
 	C = flag.Bool("C", false, "")
+	// Output
+	J        = flag.Bool("J", false, "")
+	X        = flag.Bool("X", false, "")
+	H        = flag.String("H", "", "")
+	T        = flag.String("T", "", "")
+	ndjson   = flag.Bool("ndjson", false, "")
+	format   = flag.String("format", "", "")
+	fenced   = flag.Bool("fenced", false, "")
+	baseHREF = flag.String("base-href", "", "")
+	sha256   = flag.Bool("sha256", false, "")
 )
 
 var usage = `Usage: tree [options...] [paths...]
 
+Tar archives (.tar, .tar.gz, .tgz, .tar.bz2, .tbz2) and zip archives (.zip,
+.jar, .war, .whl) are listed as directories. docker save archives (.tar) are
+listed as the root file system of their image. http:// and https:// URLs of
+directory listings, e.g: Apache and nginx autoindex pages, are crawled.
+
 Options:
     ------- Listing options -------
     -a		    All files are listed.
@@ -54,11 +100,33 @@ Options:
     -l		    Follow symbolic links like directories.
     -f		    Print the full path prefix for each file.
     -L		    Descend only level directories deep.
-    -P		    List only those files that match the pattern given.
-    -I		    Do not list files that match the given pattern.
+    -P		    List only those files that match the wild-card pattern given.
+    -I		    Do not list files that match the given wild-card pattern.
+		    Patterns are separated by |, and those with / or ** match
+		    the path. A pattern ending with / matches directories.
+		    -P and -I can be repeated.
+    --include-from  Read -P patterns from a file, one per line.
+    --exclude-from  Read -I patterns from a file, one per line.
+    --regex	    Match -P and -I as regular expressions.
     --ignore-case   Ignore case when pattern matching.
+    --prune	    Prune empty directories from the output.
+    --gitignore	    Filter out the files ignored by git, and the .git directory.
+    --min-size X    List only files of at least X bytes, e.g: 10M, 1.5G.
+    --max-size X    List only files of at most X bytes. With -d, directories
+		    are listed by their recursive size, down to the -L level.
+    --newer X	    List only files changed after X, e.g: 2h, 3d, 2024-01-01.
+    --older X	    List only files changed before X.
+    --newer-than F  List only files changed after the file F.
+    --changed-within X
+		    List only files whose status changed within X, e.g: 2h.
+    --time X	    Select time of --newer, --older and --newer-than:
+		    mtime,ctime,atime.
     --noreport	    Turn off file/directory count at end of tree listing.
     -o filename	    Output to file instead of stdout.
+    --git-rev X	    List the files of the git revision X, e.g: HEAD~5.
+    --fromfile	    Read paths from files (. for stdin) instead of the file system.
+    --fromtabfile   Like --fromfile, but read tab-indented outlines.
+    --overlay	    Merge the paths, from the lowest to the uppermost layer.
     -------- File options ---------
     -Q		    Quote filenames with double quotes.
     -p		    Print the protections for each file.
@@ -69,6 +137,11 @@ Options:
     -D		    Print the date of last modification or (-c) status change.
     --inodes	    Print inode number of each file.
     --device	    Print device ID number to which each file belongs.
+    --show-layer    Print the layer each file comes from (with --overlay).
+    --compressed    Print the compressed size of files in zip archives.
+    ------- Image options ---------
+    --image	    List OCI image layout directories as their root file system.
+    --layer N	    List what the layer N of an image adds (0 is the lowest).
     ------- Sorting options -------
     -v		    Sort files alphanumerically by version.
     -t		    Sort files by last modification time.
@@ -80,6 +153,17 @@ Options:
     ------- Graphics options ------
     -i		    Don't print indentation lines.
     -C		    Turn colorization on always.
+    -------- Output options -------
+    -J		    Prints out a JSON representation of the tree.
+    -X		    Prints out an XML representation of the tree (see tree.xsd).
+    -H baseHREF	    Prints out HTML format with baseHREF as top directory.
+    -T string	    Replace the default HTML title and H1 header with string.
+    --ndjson	    Stream one JSON object per line, as files are visited.
+    --format X	    Select output format: json,xml,ndjson,csv,tsv,dot,
+		    markdown,mermaid,yaml,ncdu,mtree.
+    --fenced	    Print markdown as a text tree, and mermaid, in a code block.
+    --base-href X   Link markdown and mermaid entries to X/relative/path.
+    --sha256	    Add the sha256 digest of files to the mtree output.
 `
 
 func main() {
@@ -111,10 +195,49 @@ func main() {
 			errAndExit(errors.New(msg))
 		}
 	}
+	// Check output format
+	if *format != "" {
+		switch *format {
+		case "json", "xml", "ndjson", "csv", "tsv", "dot", "markdown", "mermaid",
+			"yaml", "ncdu", "mtree":
+		default:
+			msg := fmt.Sprintf("output format '%s' not valid, should be one of: "+
+				"json,xml,ndjson,csv,tsv,dot,markdown,mermaid,yaml,ncdu,mtree", *format)
+			errAndExit(errors.New(msg))
+		}
+	}
+	// Check time-type
+	if *timeField != "" {
+		switch *timeField {
+		case "mtime", "ctime", "atime":
+		default:
+			msg := fmt.Sprintf("time type '%s' not valid, should be one of: "+
+				"mtime,ctime,atime", *timeField)
+			errAndExit(errors.New(msg))
+		}
+	}
+	// --changed-within is a --newer of the ctime
+	if *changed != "" {
+		if *newer != "" || *newerThan != "" || *timeField != "" && *timeField != "ctime" {
+			errAndExit(errors.New("--changed-within can't be used with --newer, --newer-than or --time"))
+		}
+		*newer, *timeField = *changed, "ctime"
+	}
+	// ncdu exports and mtree specifications have a single root
+	if (*format == "ncdu" || *format == "mtree") && len(dirs) > 1 {
+		errAndExit(fmt.Errorf("output format '%s' supports a single path", *format))
+	}
+	// Streamed directories are written before their size is known
+	if *d && (*minSize != "" || *maxSize != "") && (*ndjson || *format == "ndjson") {
+		errAndExit(errors.New("--min-size and --max-size can't be used with -d and ndjson output"))
+	}
+	// HTML base overrides the base of Markdown and Mermaid links
+	if *H != "" {
+		*baseHREF = *H
+	}
 	// Set options
 	opts := &tree.Options{
 		// Required
-		Fs:      new(ostree.FS),
 		OutFile: outFile,
 		// List
 		All:        *a,
@@ -122,19 +245,29 @@ func main() {
 		FullPath:   *f,
 		DeepLevel:  *L,
 		FollowLink: *l,
-		Pattern:    *P,
-		IPattern:   *I,
+		Patterns:   *P,
+		IPatterns:  *I,
+		Regex:      *regex,
 		IgnoreCase: *ignorecase,
+		Prune:      *prune,
+		GitIgnore:  *gitignore,
+		MinSize:    parseSize(*minSize),
+		MaxSize:    parseSize(*maxSize),
+		Newer:      parseTime(*newer),
+		Older:      parseTime(*older),
+		TimeField:  *timeField,
 		// Files
-		ByteSize: *s,
-		UnitSize: *h,
-		FileMode: *p,
-		ShowUid:  *u,
-		ShowGid:  *g,
-		LastMod:  *D,
-		Quotes:   *Q,
-		Inodes:   *inodes,
-		Device:   *device,
+		ByteSize:       *s,
+		UnitSize:       *h,
+		FileMode:       *p,
+		ShowUid:        *u,
+		ShowGid:        *g,
+		LastMod:        *D,
+		Quotes:         *Q,
+		Inodes:         *inodes,
+		Device:         *device,
+		ShowLayer:      *showLayer,
+		CompressedSize: *compressed,
 		// Sort
 		NoSort:    *U,
 		ReverSort: *r,
@@ -147,21 +280,213 @@ func main() {
 		// Graphics
 		NoIndent: *i,
 		Colorize: *C,
+		// Output
+		NoReport: *noreport,
+		JSON:     *J || *format == "json",
+		XML:      *X || *format == "xml",
+		HTML:     *H != "",
+		BaseHREF: *baseHREF,
+		Title:    *T,
+		NDJSON:   *ndjson || *format == "ndjson",
+		CSV:      *format == "csv",
+		TSV:      *format == "tsv",
+		DOT:      *format == "dot",
+		Markdown: *format == "markdown",
+		Mermaid:  *format == "mermaid",
+		YAML:     *format == "yaml",
+		NCDU:     *format == "ncdu",
+		MTree:    *format == "mtree",
+		Digest:   *sha256,
+		Fenced:   *fenced,
 	}
-	for _, dir := range dirs {
-		inf := tree.New(dir)
-		d, f := inf.Visit(opts)
+	// The reference file of --newer-than is a local file, whose time is
+	// selected by --time
+	if *newerThan != "" {
+		fi, err := os.Stat(*newerThan)
+		if err != nil {
+			errAndExit(err)
+		}
+		opts.Newer = opts.FileTime(fi)
+	}
+	// Read and check patterns
+	for _, file := range *includes {
+		opts.Patterns = append(opts.Patterns, readPatterns(file)...)
+	}
+	for _, file := range *excludes {
+		opts.IPatterns = append(opts.IPatterns, readPatterns(file)...)
+	}
+	if err := opts.Compile(); err != nil {
+		errAndExit(err)
+	}
+	printer := tree.NewPrinter(opts)
+	// The merged paths are listed under the uppermost one
+	roots := dirs
+	if *overlay {
+		roots = dirs[len(dirs)-1:]
+	}
+	for _, dir := range roots {
+		if *overlay {
+			opts.Fs, err = overlayFileSystem(dirs)
+		} else {
+			opts.Fs, err = fileSystem(dir)
+		}
+		if err != nil {
+			errAndExit(err)
+		}
+		d, f := printer.Visit(tree.New(dir))
 		nd, nf = nd+d, nf+f
-		inf.Print(opts)
+		if c, ok := opts.Fs.(io.Closer); ok {
+			c.Close()
+		}
 	}
 	// Print footer report
-	if !*noreport {
-		footer := fmt.Sprintf("\n%d directories", nd)
-		if !opts.DirsOnly {
-			footer += fmt.Sprintf(", %d files", nf)
+	printer.End(nd, nf)
+}
+
+// fileSystem returns the file system of the given path, that is the listed
+// paths if --fromfile or --fromtabfile is set, the files of the git
+// revision if --git-rev is set, the crawled listings if it's a URL, the root
+// file system if it's an image, or the archive entries if it's a tar or a
+// zip archive.
+func fileSystem(path string) (tree.Fs, error) {
+	if *fromfile {
+		return readListing(path, listfs.Read)
+	}
+	if *fromtab {
+		return readListing(path, listfs.ReadTab)
+	}
+	if *gitRev != "" {
+		return gitfs.Open(path, *gitRev)
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return httpfs.New(path), nil
+	}
+	fi, err := os.Stat(path)
+	if err == nil && fi.IsDir() && (*image || *layer >= 0) {
+		return imageFileSystem(path)
+	}
+	if err != nil || !fi.Mode().IsRegular() {
+		return new(ostree.FS), nil
+	}
+	name := strings.ToLower(path)
+	// Uncompressed archives are images if they have a manifest.
+	if strings.HasSuffix(name, ".tar") {
+		fs, err := imageFileSystem(path)
+		if !errors.Is(err, imagefs.ErrNotImage) || *layer >= 0 {
+			return fs, err
 		}
-		fmt.Fprintln(outFile, footer)
 	}
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tbz"} {
+		if strings.HasSuffix(name, ext) {
+			return tarfs.Open(path)
+		}
+	}
+	for _, ext := range []string{".zip", ".jar", ".war", ".whl"} {
+		if strings.HasSuffix(name, ext) {
+			return zipfs.Open(path)
+		}
+	}
+	return new(ostree.FS), nil
+}
+
+// imageFileSystem returns the root file system of the image at the given
+// path, or the files of one of its layers if --layer is set.
+func imageFileSystem(path string) (tree.Fs, error) {
+	fs, err := imagefs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if *layer < 0 {
+		return fs, nil
+	}
+	return fs.Layer(*layer)
+}
+
+// overlayFileSystem returns the file system that merges the given paths,
+// from the lowest to the uppermost layer. The layers are named by their
+// paths.
+func overlayFileSystem(dirs []string) (tree.Fs, error) {
+	fs := &tree.OverlayFs{Root: dirs[len(dirs)-1]}
+	for _, dir := range dirs {
+		lfs, err := fileSystem(dir)
+		if err != nil {
+			fs.Close()
+			return nil, err
+		}
+		fs.Layers = append(fs.Layers, tree.Layer{Name: dir, Fs: lfs, Root: dir})
+	}
+	return fs, nil
+}
+
+// readListing reads the listing in the given file, or in the standard input
+// if it's ".".
+func readListing(path string, read func(io.Reader) (*listfs.FS, error)) (tree.Fs, error) {
+	r := io.Reader(os.Stdin)
+	if path != "." {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	fs, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	fs.Root = path
+	return fs, nil
+}
+
+// stringList is the value of a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// listFlag defines a flag that can be repeated, e.g: -P '*.go' -P '*.mod'.
+func listFlag(name string) *stringList {
+	l := new(stringList)
+	flag.Var(l, name, "")
+	return l
+}
+
+// parseSize parses the value of a size flag, or exits.
+func parseSize(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	size, err := tree.ParseSize(s)
+	if err != nil {
+		errAndExit(err)
+	}
+	return size
+}
+
+// parseTime parses the value of a time flag, or exits.
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := tree.ParseTime(s, time.Now())
+	if err != nil {
+		errAndExit(err)
+	}
+	return t
+}
+
+// readPatterns reads the patterns in the given file, or exits.
+func readPatterns(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		errAndExit(err)
+	}
+	defer f.Close()
+	patterns, err := tree.ReadPatterns(f)
+	if err != nil {
+		errAndExit(err)
+	}
+	return patterns
 }
 
 func usageAndExit(msg string) {
@@ -29,41 +29,72 @@ func ANSIColorFormat(style string, s string) string {
 
 // ANSIColor
 func ANSIColor(node *Node, s string) string {
-	var style string
+	style, ok := ansiStyles[fileClass(node)]
+	if !ok {
+		return s
+	}
+	return ANSIColorFormat(style, s)
+}
+
+// HTMLColor wraps s with a span element. Its class attribute is the node
+// class, e.g: "dir", "exec" or "archive". Note that s is expected to be
+// HTML-escaped already.
+func HTMLColor(node *Node, s string) string {
+	class := fileClass(node)
+	if class == "" {
+		return s
+	}
+	return fmt.Sprintf(`<span class="%s">%s</span>`, class, s)
+}
+
+// ANSI styles of the file classes.
+var ansiStyles = map[string]string{
+	"exec":    "1;32",
+	"archive": "1;31",
+	"media":   "1;35",
+	"dir":     "1;34",
+	"fifo":    "40;33",
+	"socket":  "40;1;35",
+	"device":  "40;1;33",
+	"orphan":  "40;1;31",
+	"link":    "1;36",
+}
+
+// fileClass returns the class of the node used for colorization, or an
+// empty string if it's a regular file.
+func fileClass(node *Node) string {
 	var mode = node.Mode()
 	var ext = filepath.Ext(node.Name())
 	switch {
 	case contains([]string{".bat", ".btm", ".cmd", ".com", ".dll", ".exe"}, ext):
-		style = "1;32"
+		return "exec"
 	case contains([]string{".arj", ".bz2", ".deb", ".gz", ".lzh", ".rpm",
 		".tar", ".taz", ".tb2", ".tbz2", ".tbz", ".tgz", ".tz", ".tz2", ".z",
 		".zip", ".zoo"}, ext):
-		style = "1;31"
+		return "archive"
 	case contains([]string{".asf", ".avi", ".bmp", ".flac", ".gif", ".jpg",
 		"jpeg", ".m2a", ".m2v", ".mov", ".mp3", ".mpeg", ".mpg", ".ogg", ".ppm",
 		".rm", ".tga", ".tif", ".wav", ".wmv",
 		".xbm", ".xpm"}, ext):
-		style = "1;35"
+		return "media"
 	case node.IsDir() || mode&os.ModeDir != 0:
-		style = "1;34"
+		return "dir"
 	case mode&os.ModeNamedPipe != 0:
-		style = "40;33"
+		return "fifo"
 	case mode&os.ModeSocket != 0:
-		style = "40;1;35"
+		return "socket"
 	case mode&os.ModeDevice != 0 || mode&os.ModeCharDevice != 0:
-		style = "40;1;33"
+		return "device"
 	case mode&os.ModeSymlink != 0:
-		if _, err := filepath.EvalSymlinks(node.path); err != nil {
-			style = "40;1;31"
-		} else {
-			style = "1;36"
+		if node.orphan() {
+			return "orphan"
 		}
+		return "link"
 	case mode&modeExecute != 0:
-		style = "1;32"
+		return "exec"
 	default:
-		return s
+		return ""
 	}
-	return ANSIColorFormat(style, s)
 }
 
 // case-insensitive contains helper
@@ -75,5 +106,3 @@ func contains(slice []string, str string) bool {
 	}
 	return false
 }
-
-// TODO: HTMLColor
@@ -51,3 +51,23 @@ func TestFileMode(t *testing.T) {
 		}
 	}
 }
+
+func TestHTMLColor(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mode     os.FileMode
+		expected string
+	}{
+		{"simple", os.FileMode(0), "simple"},
+		{"dir", os.ModeDir, `<span class="dir">dir</span>`},
+		{"foo.jpg", os.FileMode(0), `<span class="media">foo.jpg</span>`},
+		{"bar.tar", os.FileMode(0), `<span class="archive">bar.tar</span>`},
+		{"exec", os.FileMode(syscall.S_IXUSR), `<span class="exec">exec</span>`},
+	} {
+		fi := &file{name: test.name, mode: test.mode}
+		no := &Node{FileInfo: fi}
+		if actual := HTMLColor(no, fi.name); actual != test.expected {
+			t.Errorf("\ngot:\n%+v\nexpected:\n%+v", actual, test.expected)
+		}
+	}
+}
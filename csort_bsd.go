@@ -5,6 +5,7 @@ package tree
 import (
 	"os"
 	"syscall"
+	"time"
 )
 
 func CTimeSort(f1, f2 os.FileInfo) bool {
@@ -19,3 +20,13 @@ func CTimeSort(f1, f2 os.FileInfo) bool {
 	}
 	return s1.Ctimespec.Sec < s2.Ctimespec.Sec
 }
+
+// statTimes returns the access and status change times of an os file, or
+// zero times if it's not one.
+func statTimes(fi os.FileInfo) (atime, ctime time.Time) {
+	s, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	return time.Unix(s.Atimespec.Unix()), time.Unix(s.Ctimespec.Unix())
+}
@@ -2,5 +2,15 @@
 
 package tree
 
+import (
+	"os"
+	"time"
+)
+
 // CtimeSort for unsupported OS - just compare ModTime
 var CTimeSort = ModSort
+
+// statTimes for unsupported OS - the times are unknown
+func statTimes(fi os.FileInfo) (atime, ctime time.Time) {
+	return
+}
@@ -6,6 +6,7 @@ package tree
 import (
 	"os"
 	"syscall"
+	"time"
 )
 
 func CTimeSort(f1, f2 os.FileInfo) bool {
@@ -20,3 +21,13 @@ func CTimeSort(f1, f2 os.FileInfo) bool {
 	}
 	return s1.Ctim.Sec < s2.Ctim.Sec
 }
+
+// statTimes returns the access and status change times of an os file, or
+// zero times if it's not one.
+func statTimes(fi os.FileInfo) (atime, ctime time.Time) {
+	s, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	return time.Unix(s.Atim.Unix()), time.Unix(s.Ctim.Unix())
+}
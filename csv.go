@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"time"
+)
+
+// csvPrinter prints the tree as a flat table, one row per node, with the
+// path, depth and type of the node, a column for each of the enabled
+// metadata, the link target and the error. Rows are comma-separated, or
+// tab-separated with TSV, and quoted as described in RFC 4180.
+type csvPrinter struct {
+	w *csv.Writer
+}
+
+func newCSVPrinter(opts *Options) *csvPrinter {
+	w := csv.NewWriter(opts.OutFile)
+	if opts.TSV {
+		w.Comma = '\t'
+	}
+	return &csvPrinter{w}
+}
+
+func (p *csvPrinter) begin(opts *Options) {
+	header := append([]string{"path", "depth", "type"}, csvColumns(opts)...)
+	p.w.Write(append(header, "target", "error"))
+	p.w.Flush()
+}
+
+func (p *csvPrinter) print(node *Node, i int, opts *Options) {
+	p.printNode(node, opts)
+	p.w.Flush()
+}
+
+// end writes nothing, there's no room for a report in a table.
+func (p *csvPrinter) end(dirs, files int, opts *Options) {}
+
+func (p *csvPrinter) printNode(node *Node, opts *Options) {
+	p.w.Write(node.record(opts))
+	for _, nnode := range node.nodes {
+		p.printNode(nnode, opts)
+	}
+}
+
+// csvColumns returns the names of the metadata columns enabled by the options.
+func csvColumns(opts *Options) (columns []string) {
+	if opts.Inodes {
+		columns = append(columns, "inode")
+	}
+	if opts.Device {
+		columns = append(columns, "dev")
+	}
+	if opts.FileMode {
+		columns = append(columns, "mode", "prot")
+	}
+	if opts.ShowUid {
+		columns = append(columns, "user")
+	}
+	if opts.ShowGid {
+		columns = append(columns, "group")
+	}
+	if opts.ByteSize || opts.UnitSize {
+		columns = append(columns, "size")
+	}
+	if opts.CompressedSize {
+		columns = append(columns, "compressed")
+	}
+	if opts.LastMod {
+		columns = append(columns, "time")
+	}
+	return
+}
+
+// record returns the table row of the node. Unknown values are left empty.
+func (node *Node) record(opts *Options) []string {
+	row := []string{node.path, strconv.Itoa(node.depth), node.kind()}
+	var target, err string
+	if node.err != nil {
+		err = node.errMsg()
+	}
+	if node.FileInfo == nil {
+		row = append(row, make([]string, len(csvColumns(opts)))...)
+		return append(row, target, err)
+	}
+	ok, inode, device, _, _ := getStat(node)
+	owned, _, _, user, group := getOwner(node)
+	// stat returns s if the stat data is known.
+	stat := func(s string) string {
+		if !ok {
+			return ""
+		}
+		return s
+	}
+	if opts.Inodes {
+		row = append(row, stat(strconv.FormatUint(inode, 10)))
+	}
+	if opts.Device {
+		row = append(row, stat(strconv.FormatUint(device, 10)))
+	}
+	if opts.FileMode {
+		row = append(row, node.octalMode(), node.protMode())
+	}
+	if !owned {
+		user, group = "", ""
+	}
+	if opts.ShowUid {
+		row = append(row, user)
+	}
+	if opts.ShowGid {
+		row = append(row, group)
+	}
+	if opts.ByteSize || opts.UnitSize {
+		var s string
+		if size, ok := node.size(opts); ok && opts.UnitSize {
+			s = formatBytes(size)
+		} else if ok {
+			s = strconv.FormatInt(size, 10)
+		}
+		row = append(row, s)
+	}
+	if opts.CompressedSize {
+		var s string
+		if size, ok := node.compressedSize(); ok {
+			s = strconv.FormatInt(size, 10)
+		}
+		row = append(row, s)
+	}
+	if opts.LastMod {
+		row = append(row, node.ModTime().Format(time.RFC3339))
+	}
+	if node.err == nil && node.Mode()&os.ModeSymlink != 0 {
+		var recursive bool
+		target, _, recursive = node.link(opts)
+		if recursive {
+			err = "recursive, not followed"
+		}
+	}
+	return append(row, target, err)
+}
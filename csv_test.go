@@ -0,0 +1,100 @@
+package tree
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var csvTests = []treeTest{
+	{"csv", &Options{Fs: fs, OutFile: out, CSV: true}, `path,depth,type,target,error
+root,0,directory,,
+"root/a,b",1,file,,
+"root/c ""quoted""",1,directory,,
+"root/c ""quoted""/d
+e",2,file,,
+root/bad,1,file,,stat failed
+`, 1, 2},
+	{"tsv + metadata", &Options{Fs: fs, OutFile: out, TSV: true, FileMode: true, UnitSize: true, Inodes: true, LastMod: true}, `path	depth	type	inode	mode	prot	size	time	target	error
+root	0	directory	1	0755	drwxr-xr-x	2.0K	2015-02-11T00:00:00Z		
+root/a,b	1	file	2	0644	-rw-r--r--	2.0K	2015-02-11T00:00:00Z		
+"root/c ""quoted"""	1	directory	3	0755	drwxr-xr-x	0	2015-02-11T00:00:00Z		
+"root/c ""quoted""/d
+e"	2	file	4	0644	-rw-r--r--	0	2015-02-11T00:00:00Z		
+root/bad	1	file							stat failed
+`, 1, 2},
+}
+
+func TestCSV(t *testing.T) {
+	mtime := time.Date(2015, 2, 11, 0, 0, 0, 0, time.UTC)
+	root := &file{
+		name:    "root",
+		mode:    os.ModeDir | 0755,
+		lastMod: mtime,
+		stat:    &syscall.Stat_t{Ino: 1},
+		files: []*file{
+			{name: "a,b", size: 2048, mode: 0644, lastMod: mtime, stat: &syscall.Stat_t{Ino: 2}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:    `c "quoted"`,
+				mode:    os.ModeDir | 0755,
+				lastMod: mtime,
+				stat:    &syscall.Stat_t{Ino: 3},
+				files:   []*file{{name: "d\ne", mode: 0644, lastMod: mtime, stat: &syscall.Stat_t{Ino: 4}}},
+			},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range csvTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		r := csv.NewReader(strings.NewReader(out.str))
+		if test.opts.TSV {
+			r.Comma = '\t'
+		}
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Errorf("%s: invalid output: %v", test.name, err)
+		} else if len(records) != 6 || records[4][0] != "root/c \"quoted\"/d\ne" {
+			t.Errorf("%s: unexpected records: %q", test.name, records)
+		}
+		out.clear()
+	}
+}
+
+// compressedStat is the Sys value of a file of a compressed archive.
+type compressedStat int64
+
+func (s compressedStat) PackedSize() int64 { return int64(s) }
+
+func TestCSVCompressed(t *testing.T) {
+	root := &file{
+		name:  "root",
+		mode:  os.ModeDir | 0755,
+		files: []*file{{name: "a", size: 2048, mode: 0644, stat: compressedStat(100)}, {name: "b", size: 10, mode: 0644}},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, CSV: true, ByteSize: true, CompressedSize: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	expected := `path,depth,type,size,compressed,target,error
+root,0,directory,2058,,,
+root/a,1,file,2048,100,,
+root/b,1,file,10,,,
+`
+	if !out.equal(expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+}
@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// dotPrinter prints the tree as a Graphviz graph. Every node is a vertex,
+// identified by its path, and labeled with its name and the enabled
+// metadata. Vertices are styled by their class (see: HTMLColor), and
+// symbolic links followed with FollowLink are drawn as dashed edges to
+// their targets.
+type dotPrinter struct{}
+
+// Graphviz attributes of the file classes.
+var dotStyles = map[string]string{
+	"exec":    `color="green4", fontcolor="green4"`,
+	"archive": `color="red3", fontcolor="red3"`,
+	"media":   `color="magenta3", fontcolor="magenta3"`,
+	"dir":     `shape="folder", color="blue", fontcolor="blue"`,
+	"fifo":    `color="goldenrod", fontcolor="goldenrod"`,
+	"socket":  `color="magenta", fontcolor="magenta"`,
+	"device":  `color="gold3", fontcolor="gold3"`,
+	"orphan":  `color="red", fontcolor="red", style="dashed"`,
+	"link":    `color="cyan4", fontcolor="cyan4"`,
+}
+
+func (dotPrinter) begin(opts *Options) {
+	fmt.Fprintln(opts.OutFile, "strict digraph tree {")
+	fmt.Fprintln(opts.OutFile, "\trankdir=\"LR\";")
+	fmt.Fprintln(opts.OutFile, "\tnode [shape=\"box\", fontname=\"monospace\"];")
+}
+
+func (dotPrinter) print(node *Node, i int, opts *Options) { node.printDOT(opts) }
+
+func (dotPrinter) end(dirs, files int, opts *Options) {
+	if !opts.NoReport {
+		fmt.Fprintf(opts.OutFile, "\t// %s\n", report(dirs, files, opts))
+	}
+	fmt.Fprintln(opts.OutFile, "}")
+}
+
+func (node *Node) printDOT(opts *Options) {
+	w := opts.OutFile
+	id := dotString(node.path)
+	label := node.name(opts)
+	if node.err != nil {
+		label += "\n[" + node.errMsg() + "]"
+		fmt.Fprintf(w, "\t%s [label=%s, color=\"red\", fontcolor=\"red\"];\n", id, dotString(label))
+		return
+	}
+	if opts.Quotes {
+		label = fmt.Sprintf("\"%s\"", label)
+	}
+	// The node's children, which are the target's children for a followed link.
+	parent := id
+	if node.Mode()&os.ModeSymlink != 0 {
+		target, path, fi := node.target(opts)
+		label += " -> " + target
+		if opts.FollowLink && fi != nil && fi.IsDir() {
+			node.link(opts)
+			parent = dotString(path)
+			fmt.Fprintf(w, "\t%s -> %s [style=\"dashed\"];\n", id, parent)
+		}
+	}
+	if props := node.props(opts); len(props) > 0 {
+		label += "\n" + strings.Join(strings.Fields(strings.Join(props, " ")), " ")
+	}
+	attrs := "label=" + dotString(label)
+	if style, ok := dotStyles[fileClass(node)]; ok {
+		attrs += ", " + style
+	}
+	fmt.Fprintf(w, "\t%s [%s];\n", id, attrs)
+	for _, nnode := range node.nodes {
+		fmt.Fprintf(w, "\t%s -> %s;\n", parent, dotString(nnode.path))
+		nnode.printDOT(opts)
+	}
+}
+
+// dotString returns s as a quoted DOT string.
+func dotString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,60 @@
+package tree
+
+import (
+	"os"
+	"testing"
+)
+
+var dotTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, DOT: true}, `strict digraph tree {
+	rankdir="LR";
+	node [shape="box", fontname="monospace"];
+	"root" [label="root", shape="folder", color="blue", fontcolor="blue"];
+	"root" -> "root/a.tar";
+	"root/a.tar" [label="a.tar", color="red3", fontcolor="red3"];
+	"root" -> "root/c";
+	"root/c" [label="c", shape="folder", color="blue", fontcolor="blue"];
+	"root/c" -> "root/c/say \"hi\"";
+	"root/c/say \"hi\"" [label="say \"hi\""];
+	"root" -> "root/bad";
+	"root/bad" [label="bad\n[stat failed]", color="red", fontcolor="red"];
+	// 1 directories, 2 files
+}
+`, 1, 2},
+	{"size + noreport", &Options{Fs: fs, OutFile: out, DOT: true, ByteSize: true, NoReport: true, DeepLevel: 1}, `strict digraph tree {
+	rankdir="LR";
+	node [shape="box", fontname="monospace"];
+	"root" [label="root\n100", shape="folder", color="blue", fontcolor="blue"];
+	"root" -> "root/a.tar";
+	"root/a.tar" [label="a.tar\n100", color="red3", fontcolor="red3"];
+	"root" -> "root/c";
+	"root/c" [label="c\n???????????", shape="folder", color="blue", fontcolor="blue"];
+	"root" -> "root/bad";
+	"root/bad" [label="bad\n[stat failed]", color="red", fontcolor="red"];
+}
+`, 1, 1},
+}
+
+func TestDOT(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir,
+		files: []*file{
+			{name: "a.tar", size: 100},
+			{name: "bad"}, // stat fails on this file
+			{name: "c", mode: os.ModeDir, files: []*file{{name: `say "hi"`, size: 50}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range dotTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
@@ -0,0 +1,137 @@
+package tree
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize parses a size in bytes, or in human readable units that are
+// powers of 1024, e.g: "512", "10K", "1.5G" or "2MB".
+func ParseSize(s string) (int64, error) {
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+	unit := int64(1)
+	if n := len(num); n > 0 {
+		if i := strings.IndexByte("KMGTPE", num[n-1]); i != -1 {
+			unit = []int64{KB, MB, GB, TB, PB, EB}[i]
+			num = num[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	// The size must be a finite number, that fits in an int64.
+	size := f * float64(unit)
+	if err != nil || math.IsNaN(f) || f < 0 || size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(size), nil
+}
+
+// sizeFilter reports whether the files are filtered by their size.
+func (opts *Options) sizeFilter() bool {
+	return opts.MinSize > 0 || opts.MaxSize > 0
+}
+
+// dirSizeFilter reports whether the directories are filtered by their
+// recursive size.
+func (opts *Options) dirSizeFilter() bool {
+	return opts.DirsOnly && opts.sizeFilter()
+}
+
+// inSizeRange reports whether the size is between MinSize and MaxSize.
+func (opts *Options) inSizeRange(size int64) bool {
+	return size >= opts.MinSize && (opts.MaxSize == 0 || size <= opts.MaxSize)
+}
+
+// filterDirs leaves out the files, and the directories whose recursive size
+// is not in range, and returns the number of directories that are left.
+// It goes top-down, so the size of a directory includes the directories
+// that are left out under it. The size is kept, as it's printed with -s.
+func (node *Node) filterDirs(opts *Options) (dirs int) {
+	size, err := dirRecursiveSize(opts, node)
+	node.rsize = &recursiveSize{size, err}
+	nodes := node.nodes[:0]
+	for _, nnode := range node.nodes {
+		if nnode.err == nil {
+			if !nnode.IsDir() {
+				continue
+			}
+			if size, _ := dirRecursiveSize(opts, nnode); !opts.inSizeRange(size) {
+				continue
+			}
+			dirs += 1 + nnode.filterDirs(opts)
+		}
+		nodes = append(nodes, nnode)
+	}
+	node.nodes = nodes
+	return
+}
+
+// The layouts of the dates of ParseTime.
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// The units of the durations of ParseTime, that time.ParseDuration doesn't
+// have.
+var durationUnits = map[byte]time.Duration{
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// ParseTime parses a date, e.g: "2024-01-01" or "2024-01-01 15:04", in the
+// local time zone, or a duration before now, e.g: "2h", "30m" or "3d".
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if n := len(s); err != nil && n > 1 && durationUnits[s[n-1]] != 0 {
+		var f float64
+		if f, err = strconv.ParseFloat(s[:n-1], 64); err == nil {
+			d = time.Duration(f * float64(durationUnits[s[n-1]]))
+		}
+	}
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return now.Add(-d), nil
+}
+
+// timeFilter reports whether the files are filtered by their time.
+func (opts *Options) timeFilter() bool {
+	return !opts.Newer.IsZero() || !opts.Older.IsZero()
+}
+
+// inTimeRange reports whether the time is after Newer, and before Older.
+func (opts *Options) inTimeRange(t time.Time) bool {
+	return (opts.Newer.IsZero() || t.After(opts.Newer)) && (opts.Older.IsZero() || t.Before(opts.Older))
+}
+
+// FileTime returns the time of the file that TimeField selects. The access
+// and status change times are read from the status of os and MemFs files,
+// and from the tar headers that have them. Otherwise, like in CTimeSort,
+// the modification time is used.
+func (opts *Options) FileTime(fi os.FileInfo) time.Time {
+	var atime, ctime time.Time
+	if st, ok := fi.Sys().(SysInfo); ok {
+		atime, ctime = st.Times()
+	} else {
+		atime, ctime = statTimes(fi)
+	}
+	switch {
+	case opts.TimeField == "atime" && !atime.IsZero():
+		return atime
+	case opts.TimeField == "ctime" && !ctime.IsZero():
+		return ctime
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,281 @@
+package tree
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		size int64
+		err  bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"10K", 10 * KB, false},
+		{"10k", 10 * KB, false},
+		{"1.5G", 3 * GB / 2, false},
+		{"2MB", 2 * MB, false},
+		{"2MiB", 2 * MB, false},
+		{"1E", EB, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"-1K", 0, true},
+		{"10X", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"-Inf", 0, true},
+		{"1e400", 0, true},
+		{"1e300K", 0, true},
+		{"8E", 0, true},
+		{"7E", 7 * EB, false},
+	}
+	for _, test := range tests {
+		size, err := ParseSize(test.s)
+		if (err != nil) != test.err || size != test.size {
+			t.Errorf("%q: got %d, %v, expected: %d", test.s, size, err, test.size)
+		}
+	}
+}
+
+// newSizeFs returns a MemFs with files of the given sizes.
+func newSizeFs(t *testing.T) *MemFs {
+	fs := NewMemFs()
+	for name, size := range map[string]int{
+		"root/small":           10,
+		"root/big":             3000,
+		"root/logs/a.log":      1500,
+		"root/logs/b.log":      1500,
+		"root/logs/old/c.log":  200,
+		"root/src/main.go":     100,
+		"root/src/lib/util.go": 900,
+		"root/empty/.keep":     0,
+	} {
+		if err := fs.WriteFile(name, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+var sizeTests = []treeTest{
+	{"min-size", &Options{MinSize: 1000, ByteSize: true}, `[       6000]  root
+├── [       3000]  big
+├── [          0]  empty
+├── [       3000]  logs
+│   ├── [       1500]  a.log
+│   ├── [       1500]  b.log
+│   └── [          0]  old
+└── [          0]  src
+    └── [          0]  lib
+`, 5, 3},
+	{"min-size + prune", &Options{MinSize: 1000, Prune: true}, `root
+├── big
+└── logs
+    ├── a.log
+    └── b.log
+`, 1, 3},
+	{"max-size + pattern", &Options{MaxSize: 1000, Pattern: "*.go|*.log", Prune: true}, `root
+├── logs
+│   └── old
+│       └── c.log
+└── src
+    ├── lib
+    │   └── util.go
+    └── main.go
+`, 4, 3},
+	{"min-size + dirs", &Options{MinSize: 1000, DirsOnly: true}, `root
+├── logs
+└── src
+`, 2, 0},
+	{"size range + dirs", &Options{MinSize: 200, MaxSize: 1000, DirsOnly: true, All: true}, `root
+└── src
+    └── lib
+`, 2, 0},
+	{"min-size + ndjson", &Options{MinSize: 1000, DirsOnly: true, NDJSON: true}, `{"type":"directory","path":"root","depth":0}
+{"type":"directory","path":"root/logs","depth":1}
+{"type":"directory","path":"root/src","depth":1}
+{"type":"report","directories":2}
+`, 2, 0},
+	{"min-size + dirs + size", &Options{MinSize: 1000, DirsOnly: true, ByteSize: true}, `[       7210]  root
+├── [       3200]  logs
+└── [       1000]  src
+`, 2, 0},
+}
+
+func TestSizeFilter(t *testing.T) {
+	for _, test := range sizeTests {
+		test.opts.Fs = newSizeFs(t)
+		test.opts.OutFile = out
+		inf := New("root")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if test.opts.NDJSON {
+			NewPrinter(test.opts).End(d, f)
+		}
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+	opts := &Options{Fs: newSizeFs(t), OutFile: out, MinSize: 1000, DirsOnly: true, NDJSON: true}
+	p := NewPrinter(opts)
+	p.End(p.Visit(New("root")))
+	if !strings.Contains(out.str, errDirSizeStream.Error()) {
+		t.Errorf("expected a stream error, got:\n%s", out.str)
+	}
+	out.clear()
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s   string
+		t   time.Time
+		err bool
+	}{
+		{"2h", now.Add(-2 * time.Hour), false},
+		{"90m", now.Add(-90 * time.Minute), false},
+		{"1.5d", time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), false},
+		{"2w", time.Date(2024, 5, 18, 12, 0, 0, 0, time.UTC), false},
+		{"2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), false},
+		{"2024-01-01 15:04", time.Date(2024, 1, 1, 15, 4, 0, 0, time.Local), false},
+		{"2024-01-01T10:00:00Z", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"d", time.Time{}, true},
+		{"3x", time.Time{}, true},
+		{"-2h", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+	}
+	for _, test := range tests {
+		tm, err := ParseTime(test.s, now)
+		if (err != nil) != test.err || !tm.Equal(test.t) {
+			t.Errorf("%q: got %v, %v, expected: %v", test.s, tm, err, test.t)
+		}
+	}
+}
+
+// The days of the time filter tests.
+func day(n int) time.Time { return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC) }
+
+// newTimeFs returns a MemFs with files of different times:
+//
+//	docs/guide.md: modified on day 1, changed on day 12
+//	old.txt:       modified on day 1, accessed on day 15
+//	src/util.go:   modified on day 5
+//	src/main.go:   modified on day 10
+func newTimeFs(t *testing.T) *MemFs {
+	fs := NewMemFs()
+	now := day(1)
+	fs.Clock = func() time.Time { return now }
+	for _, step := range []struct {
+		day int
+		fn  func() error
+	}{
+		{1, func() error { return fs.WriteFile("root/docs/guide.md", nil, 0644) }},
+		{1, func() error { return fs.WriteFile("root/old.txt", nil, 0644) }},
+		{1, func() error { return fs.Chtimes("root/old.txt", day(15), day(1)) }},
+		{5, func() error { return fs.WriteFile("root/src/util.go", nil, 0644) }},
+		{10, func() error { return fs.WriteFile("root/src/main.go", nil, 0644) }},
+		{12, func() error { return fs.Chmod("root/docs/guide.md", 0600) }},
+	} {
+		now = day(step.day)
+		if err := step.fn(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+var timeTests = []treeTest{
+	{"newer", &Options{Newer: day(3), Prune: true}, `root
+└── src
+    ├── main.go
+    └── util.go
+`, 1, 2},
+	{"newer + ctime", &Options{Newer: day(3), TimeField: "ctime", Prune: true}, `root
+├── docs
+│   └── guide.md
+└── src
+    ├── main.go
+    └── util.go
+`, 2, 3},
+	{"newer + atime", &Options{Newer: day(3), TimeField: "atime", Prune: true}, `root
+├── old.txt
+└── src
+    ├── main.go
+    └── util.go
+`, 1, 3},
+	{"older", &Options{Older: day(3)}, `root
+├── docs
+│   └── guide.md
+├── old.txt
+└── src
+`, 2, 2},
+	{"newer + older", &Options{Newer: day(3), Older: day(7), Prune: true, LastMod: true, Now: day(20)}, `root
+└── src
+    └── [Jan 05 00:00]  util.go
+`, 1, 1},
+	{"newer + ndjson", &Options{Newer: day(8), Prune: true, NDJSON: true, NoReport: true}, `{"type":"directory","path":"root","depth":0}
+{"type":"directory","path":"root/src","depth":1}
+{"type":"file","path":"root/src/main.go","depth":2}
+`, 1, 1},
+}
+
+func TestTimeFilter(t *testing.T) {
+	for _, test := range timeTests {
+		test.opts.Fs = newTimeFs(t)
+		test.opts.OutFile = out
+		inf := New("root")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestFileTime(t *testing.T) {
+	f, err := ioutil.TempFile("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := os.Chtimes(f.Name(), day(2), day(3)); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atime, _ := statTimes(fi); atime.IsZero() {
+		t.Skip("the access time is not supported")
+	}
+	for field, expected := range map[string]time.Time{"": day(3), "mtime": day(3), "atime": day(2)} {
+		if tm := (&Options{TimeField: field}).FileTime(fi); !tm.Equal(expected) {
+			t.Errorf("%q: got %v, expected: %v", field, tm, expected)
+		}
+	}
+	// The status of the file was changed by Chtimes.
+	if tm := (&Options{TimeField: "ctime"}).FileTime(fi); tm.Before(day(3)) {
+		t.Errorf("ctime: got %v", tm)
+	}
+}
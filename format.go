@@ -0,0 +1,345 @@
+package tree
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// printer renders visited nodes in one output format.
+type printer interface {
+	// begin writes everything that comes before the first root.
+	begin(opts *Options)
+	// print writes the i'th root node.
+	print(node *Node, i int, opts *Options)
+	// end writes the report and everything that comes after the last root.
+	end(dirs, files int, opts *Options)
+}
+
+// printer returns the printer for the selected output format.
+func (opts *Options) printer() printer {
+	switch {
+	case opts.JSON:
+		return jsonPrinter{}
+	case opts.XML:
+		return xmlPrinter{}
+	case opts.HTML:
+		return htmlPrinter{}
+	case opts.NDJSON:
+		return ndjsonPrinter{}
+	case opts.CSV || opts.TSV:
+		return newCSVPrinter(opts)
+	case opts.DOT:
+		return dotPrinter{}
+	case opts.Markdown:
+		return markdownPrinter{}
+	case opts.Mermaid:
+		return &mermaidPrinter{}
+	case opts.YAML:
+		return yamlPrinter{}
+	case opts.NCDU:
+		return ncduPrinter{}
+	case opts.MTree:
+		return &mtreePrinter{}
+	default:
+		return textPrinter{}
+	}
+}
+
+// Printer writes one or more visited trees, followed by the report, in the
+// output format selected by the options.
+// For example:
+//
+//	p := tree.NewPrinter(opts)
+//	for _, dir := range dirs {
+//		d, f := p.Visit(tree.New(dir))
+//		nd, nf = nd+d, nf+f
+//	}
+//	p.End(nd, nf)
+type Printer struct {
+	opts *Options
+	p    printer
+	n    int
+}
+
+// NewPrinter creates a new Printer and writes the output header, if the
+// format has one.
+func NewPrinter(opts *Options) *Printer {
+	p := &Printer{opts: opts, p: opts.printer()}
+	p.p.begin(opts)
+	return p
+}
+
+// Print writes a visited root node.
+func (p *Printer) Print(node *Node) {
+	p.p.print(node, p.n, p.opts)
+	p.n++
+}
+
+// Visit visits a root node and writes it, like Node.Visit followed by Print.
+// In NDJSON mode, the nodes are written as soon as they are visited, and are
+// not kept in the tree.
+func (p *Printer) Visit(node *Node) (dirs, files int) {
+	if !p.opts.NDJSON {
+		dirs, files = node.Visit(p.opts)
+		p.Print(node)
+		return
+	}
+	dirs, files = node.stream(p.opts)
+	p.n++
+	return
+}
+
+// End writes the report (unless NoReport is set) and closes the output.
+func (p *Printer) End(dirs, files int) {
+	p.p.end(dirs, files, p.opts)
+}
+
+// textPrinter is the default, indented tree output.
+type textPrinter struct{}
+
+func (textPrinter) begin(opts *Options) {}
+
+func (textPrinter) print(node *Node, i int, opts *Options) { node.print("", opts) }
+
+func (textPrinter) end(dirs, files int, opts *Options) {
+	if !opts.NoReport {
+		fmt.Fprintf(opts.OutFile, "\n%s\n", report(dirs, files, opts))
+	}
+}
+
+// report returns the directory and file counts, e.g: "2 directories, 3 files".
+func report(dirs, files int, opts *Options) string {
+	s := fmt.Sprintf("%d directories", dirs)
+	if !opts.DirsOnly {
+		s += fmt.Sprintf(", %d files", files)
+	}
+	return s
+}
+
+// name returns the name the node is displayed with.
+func (node *Node) name(opts *Options) string {
+	if node.depth == 0 || opts.FullPath {
+		return node.path
+	}
+	return filepath.Base(node.path)
+}
+
+// href returns the link of a node, located at rel under the root.
+// Links to directories end with a slash, and without BaseHREF they are
+// relative to the root.
+func (node *Node) href(rel string, opts *Options) string {
+	var segs []string
+	if base := strings.TrimSuffix(opts.BaseHREF, "/"); base != "" {
+		segs = append(segs, base)
+	}
+	if rel != "" {
+		for _, seg := range strings.Split(rel, "/") {
+			segs = append(segs, url.PathEscape(seg))
+		}
+	}
+	href := strings.Join(segs, "/")
+	switch {
+	case href == "":
+		href = "./"
+	case node.IsDir():
+		href += "/"
+	}
+	return href
+}
+
+// errMsg returns the node's error without the operation and path prefix.
+func (node *Node) errMsg() string {
+	err := node.err.Error()
+	if msgs := strings.Split(err, ": "); len(msgs) > 1 {
+		err = msgs[1]
+	}
+	return err
+}
+
+// kind returns the node type, using the same names as GNU tree does.
+func (node *Node) kind() string {
+	if node.FileInfo == nil {
+		return "file"
+	}
+	mode := node.Mode()
+	switch {
+	case node.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "link"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "char"
+	case mode&os.ModeDevice != 0:
+		return "block"
+	default:
+		return "file"
+	}
+}
+
+// size returns the node size. Directories report their recursive size,
+// and ok is false if it could not be calculated. In NDJSON mode, they report
+// the size of the entry itself, as their content is not known yet when they
+// are streamed.
+func (node *Node) size(opts *Options) (size int64, ok bool) {
+	if !node.IsDir() || opts.NDJSON {
+		return node.Size(), true
+	}
+	size, err := dirRecursiveSize(opts, node)
+	return size, err == nil || size > 0
+}
+
+// compressedSize returns the size of the file in its archive, if the
+// file system has it.
+func (node *Node) compressedSize() (size int64, ok bool) {
+	if c, ok := node.Sys().(PackedSizer); ok && !node.IsDir() {
+		return c.PackedSize(), true
+	}
+	return
+}
+
+// modTime returns the last modification time, formatted like `ls -l` does.
+func (node *Node) modTime(opts *Options) string {
+	t := opts.Now
+	if t.IsZero() {
+		t = time.Now()
+	}
+	format := "Jan 02 15:04"
+	if node.ModTime().Year() != t.Year() {
+		format = "Jan 02  2006"
+	}
+	return node.ModTime().Format(format)
+}
+
+// octalMode returns the permission bits in octal, e.g: "0644".
+func (node *Node) octalMode() string {
+	return fmt.Sprintf("%04o", node.Mode().Perm())
+}
+
+// protMode returns the mode in the format of ls, e.g: "lrwxrwxrwx", as
+// GNU tree writes it. os.FileMode.String uses other letters for the file
+// types, e.g: "Lrwxrwxrwx", and for the setuid, setgid and sticky bits.
+func (node *Node) protMode() string {
+	m := node.Mode()
+	b := []byte("----------")
+	switch {
+	case m&os.ModeDir != 0:
+		b[0] = 'd'
+	case m&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case m&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case m&os.ModeSocket != 0:
+		b[0] = 's'
+	case m&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case m&os.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := range rwx {
+		if m&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	for _, special := range []struct {
+		bit  os.FileMode
+		i    int
+		char byte
+	}{{os.ModeSetuid, 3, 's'}, {os.ModeSetgid, 6, 's'}, {os.ModeSticky, 9, 't'}} {
+		if m&special.bit == 0 {
+			continue
+		}
+		if b[special.i] == '-' {
+			// The bit is set, but not the execute permission.
+			b[special.i] = special.char - 'a' + 'A'
+		} else {
+			b[special.i] = special.char
+		}
+	}
+	return string(b)
+}
+
+// field is a metadata key/value pair, as written by the structured output
+// formats. The value is either a string or an integer.
+type field struct {
+	key   string
+	value interface{}
+}
+
+// fields returns the node metadata enabled by the options, using the same
+// keys as the JSON and XML outputs of GNU tree.
+func (node *Node) fields(opts *Options) (fields []field) {
+	ok, inode, device, _, _ := getStat(node)
+	owned, _, _, user, group := getOwner(node)
+	if ok && opts.Inodes {
+		fields = append(fields, field{"inode", inode})
+	}
+	if ok && opts.Device {
+		fields = append(fields, field{"dev", device})
+	}
+	if opts.FileMode {
+		fields = append(fields, field{"mode", node.octalMode()}, field{"prot", node.protMode()})
+	}
+	if owned && opts.ShowUid {
+		fields = append(fields, field{"user", user})
+	}
+	if owned && opts.ShowGid {
+		fields = append(fields, field{"group", group})
+	}
+	if opts.ByteSize || opts.UnitSize {
+		if size, ok := node.size(opts); ok {
+			fields = append(fields, field{"size", size})
+		}
+	}
+	if size, ok := node.compressedSize(); ok && opts.CompressedSize {
+		fields = append(fields, field{"compressed", size})
+	}
+	if opts.LastMod {
+		fields = append(fields, field{"time", node.modTime(opts)})
+	}
+	if layer := node.Layer(); opts.ShowLayer && layer != "" {
+		fields = append(fields, field{"layer", layer})
+	}
+	return
+}
+
+// getOwner returns the owner ids of fi, and their names. The owners of a
+// SysInfo are used as is, and the others are looked up in the system.
+func getOwner(fi os.FileInfo) (ok bool, uid, gid uint64, user, group string) {
+	if st, ok := fi.Sys().(SysInfo); ok {
+		uid, gid, user, group = st.Owner()
+		return true, uid, gid, user, group
+	}
+	if ok, _, _, uid, gid = getStat(fi); ok {
+		return true, uid, gid, lookupUser(uid), lookupGroup(gid)
+	}
+	return
+}
+
+// lookupUser returns the user name of uid, or uid itself if it is unknown.
+func lookupUser(uid uint64) string {
+	id := strconv.FormatUint(uid, 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// lookupGroup returns the group name of gid, or gid itself if it is unknown.
+func lookupGroup(gid uint64) string {
+	id := strconv.FormatUint(gid, 10)
+	if g, err := user.LookupGroupId(id); err == nil {
+		return g.Name
+	}
+	return id
+}
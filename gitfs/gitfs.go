@@ -0,0 +1,280 @@
+// Package gitfs implements tree.Fs over a revision of a local git
+// repository. The objects are read from the loose objects and packfiles of
+// the repository, without touching its work tree.
+package gitfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// Modes of tree entries.
+const (
+	ModeTree       = 0040000
+	ModeFile       = 0100644
+	ModeExecutable = 0100755
+	ModeSymlink    = 0120000
+	ModeSubmodule  = 0160000
+)
+
+// Entry is the tree entry of a file, that is returned by the Sys method of
+// its FileInfo.
+type Entry struct {
+	// Mode is the git mode of the entry, e.g: ModeExecutable.
+	Mode uint32
+	// Hash is the hex-encoded name of the object of the entry.
+	Hash string
+}
+
+// FS is a tree.Fs of a revision of a local repository. Files have the
+// permissions of their mode in the tree, the size of their blob, and the
+// time of the commit. Submodules are listed as empty directories.
+type FS struct {
+	// Root is the path of the work tree directory that is listed.
+	Root    string
+	repo    *repo
+	modTime time.Time
+	files   map[string]*file
+}
+
+// file is a tree entry, and the entries of its tree if it's a directory
+// that was read.
+type file struct {
+	entry
+	size    int64
+	entries []entry
+}
+
+// Open returns the FS of the given revision, e.g: "HEAD~5", "v1.0" or
+// "4b825dc", in the repository that contains path. The FS is rooted at
+// path, and lists the directory of path in the revision.
+func Open(path, rev string) (*FS, error) {
+	r, prefix, err := findRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := open(r, prefix, rev)
+	if err != nil {
+		r.db.close()
+		return nil, err
+	}
+	fs.Root = path
+	return fs, nil
+}
+
+func open(r *repo, prefix, rev string) (*FS, error) {
+	commit, err := r.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	tree, modTime, err := r.commit(commit)
+	if err != nil {
+		return nil, err
+	}
+	fs := &FS{Root: ".", repo: r, modTime: modTime, files: make(map[string]*file)}
+	fs.files["."] = &file{entry: entry{mode: ModeTree, hash: tree}}
+	// The work tree directory becomes the root.
+	if prefix != "" {
+		f, err := fs.file(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if f.mode != ModeTree {
+			return nil, errors.New(prefix + ": not a directory in " + rev)
+		}
+		fs.files = map[string]*file{".": f}
+	}
+	return fs, nil
+}
+
+// Close closes the packfiles of the repository.
+func (fs *FS) Close() error {
+	fs.repo.db.close()
+	return nil
+}
+
+// file returns the file with the given slash-separated path, relative to
+// the root. The trees on the way are read, and kept.
+func (fs *FS) file(rel string) (*file, error) {
+	if f, ok := fs.files[rel]; ok {
+		return f, nil
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil, os.ErrNotExist
+	}
+	parent, err := fs.file(path.Dir(rel))
+	if err != nil {
+		return nil, err
+	}
+	if parent.mode != ModeTree {
+		return nil, os.ErrNotExist
+	}
+	if parent.entries == nil {
+		if parent.entries, err = fs.repo.tree(parent.hash); err != nil {
+			return nil, err
+		}
+	}
+	name := path.Base(rel)
+	for _, e := range parent.entries {
+		if e.name != name {
+			continue
+		}
+		f := &file{entry: e}
+		if e.mode != ModeTree && e.mode != ModeSubmodule {
+			if _, f.size, _, err = fs.repo.db.read(e.hash, false, 0); err != nil {
+				return nil, err
+			}
+		}
+		fs.files[rel] = f
+		return f, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// lookup returns the file with the given name.
+func (fs *FS) lookup(op, name string) (*file, error) {
+	rel, err := vpath.Rel(fs.Root, name)
+	if err == nil {
+		var f *file
+		if f, err = fs.file(rel); err == nil {
+			return f, nil
+		}
+	}
+	return nil, &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// Stat returns the FileInfo of the named file.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	f, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if f == fs.files["."] {
+		name = fs.Root
+	}
+	return &fileInfo{name: filepath.Base(name), f: f, modTime: fs.modTime}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	f, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.mode == ModeSubmodule {
+		return nil, nil
+	}
+	if f.entries == nil {
+		if f.entries, err = fs.repo.tree(f.hash); err != nil {
+			return nil, &os.PathError{Op: "readdir", Path: name, Err: err}
+		}
+	}
+	names := make([]string, len(f.entries))
+	for i, e := range f.entries {
+		names[i] = e.name
+	}
+	return names, nil
+}
+
+// Open opens the content of the named file.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	f, err := fs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := fs.repo.db.object(f.hash, "blob")
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Readlink returns the target of the named symbolic link, and the path it
+// resolves to. Absolute targets are outside of the repository, and they
+// are resolved as is.
+func (fs *FS) Readlink(name string) (target, resolved string, err error) {
+	f, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", "", err
+	}
+	if f.mode != ModeSymlink {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	if target, err = fs.linkname(f); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	p := target
+	if !path.IsAbs(p) {
+		rel, _ := filepath.Rel(fs.Root, name)
+		p = path.Dir(filepath.ToSlash(rel)) + "/" + p
+	}
+	if resolved, err = fs.eval(p); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, resolved, nil
+}
+
+// linkname returns the target of a symbolic link, that is its blob.
+func (fs *FS) linkname(f *file) (string, error) {
+	b, err := fs.repo.db.object(f.hash, "blob")
+	return string(b), err
+}
+
+// eval returns the path of rel after the evaluation of its symbolic links,
+// like filepath.EvalSymlinks. Missing entries are resolved as is, and so
+// are absolute paths, that are out of the repository.
+func (fs *FS) eval(rel string) (string, error) {
+	r := vpath.Resolver{Readlink: func(p string) (string, bool, error) {
+		if path.IsAbs(p) {
+			return "", false, nil
+		}
+		f, err := fs.file(p)
+		if err != nil || f.mode != ModeSymlink {
+			return "", false, nil
+		}
+		target, err := fs.linkname(f)
+		return target, true, err
+	}}
+	resolved, err := r.Eval(rel)
+	if err != nil || path.IsAbs(resolved) {
+		return filepath.FromSlash(resolved), err
+	}
+	return filepath.Join(fs.Root, filepath.FromSlash(resolved)), nil
+}
+
+// fileInfo is the FileInfo of a tree entry.
+type fileInfo struct {
+	name    string
+	f       *file
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.f.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.Mode().IsDir() }
+
+func (fi *fileInfo) Mode() os.FileMode {
+	switch fi.f.mode {
+	case ModeTree, ModeSubmodule:
+		return os.ModeDir | 0755
+	case ModeSymlink:
+		return os.ModeSymlink | 0777
+	case ModeExecutable:
+		return 0755
+	}
+	return 0644
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return &Entry{Mode: fi.f.mode, Hash: fi.f.hash.String()}
+}
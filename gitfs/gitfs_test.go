@@ -0,0 +1,193 @@
+package gitfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a8m/tree"
+)
+
+// repository creates a repository with two commits, and a tag on the
+// first one. The submodule of the first commit is removed in the second. It returns the path of the work tree.
+func repository(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := ioutil.TempDir("", "gitfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	files := map[string]string{
+		"README.md":        "# tree\n",
+		"bin/run":          "#!/bin/sh\n",
+		"src/a/a.go":       strings.Repeat("package a\n", 100),
+		"src/b/b.go":       "package b\n",
+		"src/b/README.md":  "b\n",
+		"docs/index.md":    "index\n",
+		"docs/guide/a.txt": "a\n",
+	}
+	for name, content := range files {
+		write(t, dir, name, content)
+	}
+	os.Chmod(filepath.Join(dir, "bin/run"), 0755)
+	os.Symlink("src/b", filepath.Join(dir, "b"))
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", ".")
+	git(t, dir, "update-index", "--add", "--cacheinfo", "160000,4b825dc642cb6eb9a060e54bf8d69288fbee4904,lib/sub")
+	git(t, dir, "commit", "-q", "-m", "first")
+	git(t, dir, "tag", "-a", "-m", "v1", "v1")
+	write(t, dir, "src/a/a.go", strings.Repeat("package a\n", 100)+"func A() {}\n")
+	git(t, dir, "rm", "-q", "-r", "docs")
+	git(t, dir, "commit", "-q", "-a", "-m", "second")
+	return dir
+}
+
+func write(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=tree", "-c", "user.email=tree@example.com"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_COMMITTER_DATE=1136214245 +0000", "GIT_AUTHOR_DATE=1136214245 +0000")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+var tests = []struct {
+	name     string
+	rev      string
+	root     string
+	opts     *tree.Options
+	expected string
+}{
+	{"head", "HEAD", ".", &tree.Options{FileMode: true, ByteSize: true}, `[       1046]  .
+├── [-rw-r--r--           7]  README.md
+├── [Lrwxrwxrwx           5]  b -> src/b
+├── [         10]  bin
+│   └── [-rwxr-xr-x          10]  run
+└── [       1024]  src
+    ├── [       1012]  a
+    │   └── [-rw-r--r--        1012]  a.go
+    └── [         12]  b
+        ├── [-rw-r--r--           2]  README.md
+        └── [-rw-r--r--          10]  b.go
+`},
+	{"parent", "HEAD~1", "docs", &tree.Options{}, `docs
+├── guide
+│   └── a.txt
+└── index.md
+`},
+	{"submodule", "HEAD~1", "lib", &tree.Options{}, `lib
+└── sub
+`},
+	{"tag", "v1^0", "src", &tree.Options{ByteSize: true}, `[       1012]  src
+├── [       1000]  a
+│   └── [       1000]  a.go
+└── [         12]  b
+    ├── [          2]  README.md
+    └── [         10]  b.go
+`},
+	{"follow", "master^", ".", &tree.Options{FollowLink: true, Pattern: "b*|README*", Prune: true, DeepLevel: 1}, `.
+├── README.md
+└── b -> src/b
+    ├── README.md
+    └── b.go
+`},
+}
+
+func TestFS(t *testing.T) {
+	dir := repository(t)
+	git(t, dir, "branch", "-M", "master")
+	check := func(kind string) {
+		for _, test := range tests {
+			fs, err := Open(filepath.Join(dir, test.root), test.rev)
+			if err != nil {
+				t.Fatalf("%s %s: %v", kind, test.name, err)
+			}
+			fs.Root = test.root
+			out := new(bytes.Buffer)
+			test.opts.Fs = fs
+			test.opts.OutFile = out
+			inf := tree.New(test.root)
+			inf.Visit(test.opts)
+			inf.Print(test.opts)
+			if actual := out.String(); actual != test.expected {
+				t.Errorf("%s %s:\nactual\n%s\n != expect\n%s\n", kind, test.name, actual, test.expected)
+			}
+			fs.Close()
+		}
+		// The content of the blobs, and the short names of commits.
+		fs, err := Open(dir, git(t, dir, "rev-parse", "--short", "HEAD~1"))
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		defer fs.Close()
+		for _, name := range []string{"README.md", "src/a/a.go", "docs/guide/a.txt"} {
+			rc, err := fs.Open(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("%s %s: %v", kind, name, err)
+			}
+			b, _ := ioutil.ReadAll(rc)
+			if expected := git(t, dir, "show", "HEAD~1:"+name); strings.TrimSpace(string(b)) != expected {
+				t.Errorf("%s %s: got %q, expected %q", kind, name, b, expected)
+			}
+		}
+		fi, err := fs.Stat(filepath.Join(dir, "lib/sub"))
+		if err != nil || fi.Sys().(*Entry).Mode != ModeSubmodule {
+			t.Errorf("%s: expected a submodule, got %v, %v", kind, fi, err)
+		}
+	}
+	check("loose")
+	// Pack the objects and the refs, with deltas.
+	git(t, dir, "gc", "-q", "--aggressive")
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".git/objects/pack/*.idx")); len(matches) == 0 {
+		t.Fatal("expected a packfile")
+	}
+	check("packed")
+}
+
+func TestRevisions(t *testing.T) {
+	dir := repository(t)
+	r, prefix, err := findRepo(filepath.Join(dir, "src", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.db.close()
+	if prefix != "src/a" {
+		t.Errorf("got prefix %q, expected %q", prefix, "src/a")
+	}
+	for _, rev := range []string{"HEAD", "HEAD~", "HEAD^", "HEAD~1", "HEAD^1", "v1", "v1^0", "HEAD~1^0"} {
+		h, err := r.resolve(rev)
+		if err != nil {
+			t.Errorf("%s: %v", rev, err)
+			continue
+		}
+		if expected := git(t, dir, "rev-parse", rev+"^{commit}"); h.String() != expected {
+			t.Errorf("%s: got %s, expected %s", rev, h, expected)
+		}
+	}
+	for _, rev := range []string{"HEAD~2", "HEAD^2", "nope", "HEAD@1"} {
+		if _, err := r.resolve(rev); err == nil {
+			t.Errorf("%s: expected an error", rev)
+		}
+	}
+	if _, err := Open(filepath.Join(dir, "README.md"), "HEAD"); err == nil {
+		t.Error("expected an error for a file root")
+	}
+}
@@ -0,0 +1,264 @@
+package gitfs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Object types, as they are numbered in packfiles.
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+var typeNames = map[int]string{objCommit: "commit", objTree: "tree", objBlob: "blob", objTag: "tag"}
+
+// pack is a packfile, and its version 2 index.
+type pack struct {
+	f       *os.File
+	fanout  [256]uint32
+	names   []byte // sorted object names, 20 bytes each
+	offsets []byte // 4 bytes each
+	large   []byte // 8 bytes each
+}
+
+// openPack opens the packfile of the given index file.
+func openPack(idxPath string) (*pack, error) {
+	idx, err := ioutil.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.HasPrefix(idx, []byte("\377tOc\x00\x00\x00\x02")) {
+		return nil, fmt.Errorf("%s: unsupported pack index version", idxPath)
+	}
+	p := new(pack)
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	start := 8 + 256*4
+	if len(idx) < start+n*(20+4+4) {
+		return nil, fmt.Errorf("%s: truncated pack index", idxPath)
+	}
+	p.names = idx[start : start+n*20]
+	start += n * (20 + 4) // names, and their CRCs
+	p.offsets = idx[start : start+n*4]
+	p.large = idx[start+n*4:]
+	if p.f, err = os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// find returns the offset of the object in the packfile.
+func (p *pack) find(h hash) (int64, bool) {
+	lo, hi := 0, int(p.fanout[h[0]])
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i == hi || !bytes.Equal(p.names[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+	off := int64(binary.BigEndian.Uint32(p.offsets[i*4:]))
+	if off&0x80000000 != 0 {
+		j := int(off & 0x7fffffff)
+		if len(p.large) < (j+1)*8 {
+			return 0, false
+		}
+		off = int64(binary.BigEndian.Uint64(p.large[j*8:]))
+	}
+	return off, true
+}
+
+// prefix returns the names of the objects that start with the given hex prefix.
+func (p *pack) prefix(hex string) (hs []hash) {
+	for i := 0; i < len(p.names)/20; i++ {
+		var h hash
+		copy(h[:], p.names[i*20:])
+		if strings.HasPrefix(h.String(), hex) {
+			hs = append(hs, h)
+		}
+	}
+	return
+}
+
+// header reads the header of the object at the given offset. It returns
+// the type and size of the object, and a reader positioned after it.
+func (p *pack) header(off int64) (typ int, size int64, r *bufio.Reader, err error) {
+	r = bufio.NewReader(io.NewSectionReader(p.f, off, 1<<62))
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	typ, size = int(c>>4&7), int64(c&15)
+	for shift := uint(4); c&0x80 != 0; shift += 7 {
+		if c, err = r.ReadByte(); err != nil {
+			return 0, 0, nil, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+	return typ, size, r, nil
+}
+
+// base reads the base of a delta object, that is the offset of the base
+// object for objOfsDelta, or its name for objRefDelta.
+func (p *pack) base(off int64, typ int, r *bufio.Reader) (int64, hash, error) {
+	var h hash
+	if typ == objRefDelta {
+		_, err := io.ReadFull(r, h[:])
+		return 0, h, err
+	}
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, h, err
+	}
+	rel := int64(c & 0x7f)
+	for c&0x80 != 0 {
+		if c, err = r.ReadByte(); err != nil {
+			return 0, h, err
+		}
+		rel = (rel+1)<<7 | int64(c&0x7f)
+	}
+	return off - rel, h, nil
+}
+
+// The maximum length of the delta chains that are resolved.
+const maxDeltas = 10000
+
+// object reads the object at the given offset. If data is false, only the
+// type and size of the object are read. depth is the length of the delta
+// chain that is resolved.
+func (p *pack) object(db *objects, off int64, data bool, depth int) (typ int, size int64, b []byte, err error) {
+	typ, size, r, err := p.header(off)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if typ != objOfsDelta && typ != objRefDelta {
+		if !data {
+			return typ, size, nil, nil
+		}
+		b, err = inflate(r, size)
+		return typ, size, b, err
+	}
+	boff, bh, err := p.base(off, typ, r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer zr.Close()
+	delta := bufio.NewReader(zr)
+	if _, err = binary.ReadUvarint(delta); err != nil {
+		return 0, 0, nil, err
+	}
+	dsize, err := binary.ReadUvarint(delta)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if depth > maxDeltas {
+		return 0, 0, nil, errors.New("delta chain too long")
+	}
+	var base []byte
+	if typ == objOfsDelta {
+		typ, _, base, err = p.object(db, boff, data, depth+1)
+	} else {
+		var name string
+		name, _, base, err = db.read(bh, data, depth+1)
+		typ = typeNumber(name)
+	}
+	if err != nil || !data {
+		return typ, int64(dsize), nil, err
+	}
+	b, err = patch(base, delta, dsize)
+	return typ, int64(dsize), b, err
+}
+
+// patch applies the instructions of a delta to base.
+func patch(base []byte, delta *bufio.Reader, size uint64) ([]byte, error) {
+	b := make([]byte, 0, size)
+	for {
+		op, err := delta.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case op&0x80 != 0: // copy from base
+			var off, n uint32
+			for i := uint(0); i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				c, err := delta.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				if i < 4 {
+					off |= uint32(c) << (8 * i)
+				} else {
+					n |= uint32(c) << (8 * (i - 4))
+				}
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if uint64(off)+uint64(n) > uint64(len(base)) {
+				return nil, errors.New("invalid delta")
+			}
+			b = append(b, base[off:off+n]...)
+		case op != 0: // insert
+			start := len(b)
+			b = append(b, make([]byte, op)...)
+			if _, err := io.ReadFull(delta, b[start:]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("invalid delta")
+		}
+	}
+	if uint64(len(b)) != size {
+		return nil, errors.New("invalid delta size")
+	}
+	return b, nil
+}
+
+// inflate reads the zlib compressed data of the given size from r.
+func inflate(r io.Reader, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	b := make([]byte, size)
+	_, err = io.ReadFull(zr, b)
+	return b, err
+}
+
+func typeNumber(name string) int {
+	for typ, n := range typeNames {
+		if n == name {
+			return typ
+		}
+	}
+	return 0
+}
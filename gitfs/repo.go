@@ -0,0 +1,465 @@
+package gitfs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hash is the SHA-1 name of an object.
+type hash [20]byte
+
+func (h hash) String() string { return hex.EncodeToString(h[:]) }
+
+func parseHash(s string) (h hash, ok bool) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(h) {
+		return h, false
+	}
+	copy(h[:], b)
+	return h, true
+}
+
+// objects is the object database of a repository, that is the loose
+// objects and the packfiles of its object directory and its alternates.
+type objects struct {
+	dirs  []string
+	packs []*pack
+}
+
+// openObjects opens the object database in the given directory.
+func openObjects(dir string) (*objects, error) {
+	db := new(objects)
+	dirs := []string{dir}
+	for len(dirs) > 0 && len(db.dirs) < 16 {
+		dir, dirs = dirs[0], dirs[1:]
+		db.dirs = append(db.dirs, dir)
+		idxs, _ := filepath.Glob(filepath.Join(dir, "pack", "*.idx"))
+		for _, idx := range idxs {
+			p, err := openPack(idx)
+			if err != nil {
+				db.close()
+				return nil, err
+			}
+			db.packs = append(db.packs, p)
+		}
+		b, _ := ioutil.ReadFile(filepath.Join(dir, "info", "alternates"))
+		for _, line := range strings.Split(string(b), "\n") {
+			if line = strings.TrimSpace(line); line == "" || line[0] == '#' {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line)
+			}
+			dirs = append(dirs, line)
+		}
+	}
+	return db, nil
+}
+
+func (db *objects) close() {
+	for _, p := range db.packs {
+		p.f.Close()
+	}
+}
+
+// read reads the object with the given name. If data is false, only its
+// type and size are read.
+func (db *objects) read(h hash, data bool, depth int) (typ string, size int64, b []byte, err error) {
+	for _, p := range db.packs {
+		if off, ok := p.find(h); ok {
+			n, size, b, err := p.object(db, off, data, depth)
+			if err == nil && typeNames[n] == "" {
+				err = fmt.Errorf("object %s: unknown type %d", h, n)
+			}
+			return typeNames[n], size, b, err
+		}
+	}
+	name := h.String()
+	for _, dir := range db.dirs {
+		f, err := os.Open(filepath.Join(dir, name[:2], name[2:]))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", 0, nil, err
+		}
+		defer f.Close()
+		return readLoose(f, data)
+	}
+	return "", 0, nil, fmt.Errorf("object %s: not found", h)
+}
+
+// readLoose reads a loose object, that is a zlib stream of its header,
+// "<type> <size>\x00", followed by its data.
+func readLoose(r io.Reader, data bool) (typ string, size int64, b []byte, err error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+	header, err := br.ReadString(0)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	fields := strings.Fields(strings.TrimSuffix(header, "\x00"))
+	if len(fields) != 2 {
+		return "", 0, nil, errors.New("invalid object header")
+	}
+	typ = fields[0]
+	if size, err = strconv.ParseInt(fields[1], 10, 64); err != nil || !data {
+		return typ, size, nil, err
+	}
+	b = make([]byte, size)
+	_, err = io.ReadFull(br, b)
+	return typ, size, b, err
+}
+
+// object reads the content of the object, that must be of the given type.
+func (db *objects) object(h hash, typ string) ([]byte, error) {
+	t, _, b, err := db.read(h, true, 0)
+	if err != nil {
+		return nil, err
+	}
+	if t != typ {
+		return nil, fmt.Errorf("object %s: is a %s, not a %s", h, t, typ)
+	}
+	return b, nil
+}
+
+// prefix returns the names of the objects that start with the given hex prefix.
+func (db *objects) prefix(s string) []hash {
+	found := make(map[hash]bool)
+	for _, p := range db.packs {
+		for _, h := range p.prefix(s) {
+			found[h] = true
+		}
+	}
+	for _, dir := range db.dirs {
+		names, _ := filepath.Glob(filepath.Join(dir, s[:2], s[2:]+"*"))
+		for _, name := range names {
+			if h, ok := parseHash(s[:2] + filepath.Base(name)); ok {
+				found[h] = true
+			}
+		}
+	}
+	hs := make([]hash, 0, len(found))
+	for h := range found {
+		hs = append(hs, h)
+	}
+	return hs
+}
+
+// repo is a local repository.
+type repo struct {
+	gitDir    string // the git directory of the work tree, with its HEAD
+	commonDir string // the directory of the refs and objects
+	db        *objects
+}
+
+// findRepo returns the repository that contains the given path, and the
+// slash-separated path of the work tree directory relative to its top
+// level directory.
+func findRepo(path string) (*repo, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var rel []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if gitDir, ok := gitDirOf(dir); ok {
+			r, err := openRepo(gitDir)
+			if err != nil {
+				return nil, "", err
+			}
+			for i, j := 0, len(rel)-1; i < j; i, j = i+1, j-1 {
+				rel[i], rel[j] = rel[j], rel[i]
+			}
+			return r, strings.Join(rel, "/"), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return nil, "", fmt.Errorf("%s: not a git repository", path)
+		}
+		rel = append(rel, filepath.Base(dir))
+	}
+}
+
+// gitDirOf returns the git directory of dir, that is its ".git" directory,
+// the directory that its ".git" file points to, or dir itself if it's a
+// bare repository.
+func gitDirOf(dir string) (string, bool) {
+	dotGit := filepath.Join(dir, ".git")
+	fi, err := os.Stat(dotGit)
+	switch {
+	case err == nil && fi.IsDir():
+		return dotGit, true
+	case err == nil:
+		b, err := ioutil.ReadFile(dotGit)
+		if err != nil || !bytes.HasPrefix(b, []byte("gitdir: ")) {
+			return "", false
+		}
+		gitDir := strings.TrimSpace(string(b[len("gitdir: "):]))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+		return gitDir, true
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+func openRepo(gitDir string) (*repo, error) {
+	r := &repo{gitDir: gitDir, commonDir: gitDir}
+	if b, err := ioutil.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		dir := strings.TrimSpace(string(b))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(gitDir, dir)
+		}
+		r.commonDir = dir
+	}
+	db, err := openObjects(filepath.Join(r.commonDir, "objects"))
+	if err != nil {
+		return nil, err
+	}
+	r.db = db
+	return r, nil
+}
+
+// The maximum number of symbolic refs that are followed.
+const maxSymrefs = 5
+
+// ref returns the object that the ref with the given full name points to.
+func (r *repo) ref(name string) (hash, bool) {
+	for i := 0; i < maxSymrefs; i++ {
+		dir := r.commonDir
+		if !strings.HasPrefix(name, "refs/") {
+			dir = r.gitDir // HEAD and the other pseudo refs
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return r.packedRef(name)
+		}
+		s := strings.TrimSpace(string(b))
+		if !strings.HasPrefix(s, "ref: ") {
+			return parseHash(s)
+		}
+		name = strings.TrimPrefix(s, "ref: ")
+	}
+	return hash{}, false
+}
+
+// packedRef returns the object of the ref in the packed-refs file.
+func (r *repo) packedRef(name string) (hash, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return hash{}, false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == name {
+			return parseHash(fields[0])
+		}
+	}
+	return hash{}, false
+}
+
+// resolve returns the commit of the given revision. It supports the names
+// of objects (full or abbreviated), refs, and the "~<n>" and "^<n>"
+// suffixes of gitrevisions(7).
+func (r *repo) resolve(rev string) (hash, error) {
+	i := strings.IndexAny(rev, "~^")
+	if i == -1 {
+		i = len(rev)
+	}
+	name, suffix := rev[:i], rev[i:]
+	if name == "" {
+		name = "HEAD"
+	}
+	h, err := r.name(name)
+	if err != nil {
+		return h, err
+	}
+	if h, err = r.peel(h); err != nil {
+		return h, err
+	}
+	for suffix != "" {
+		op := suffix[0]
+		j := 1
+		for j < len(suffix) && suffix[j] >= '0' && suffix[j] <= '9' {
+			j++
+		}
+		n := 1
+		if j > 1 {
+			n, _ = strconv.Atoi(suffix[1:j])
+		}
+		switch {
+		case op == '~':
+			for ; n > 0 && err == nil; n-- {
+				h, err = r.parent(h, 1)
+			}
+		case op == '^' && n > 0:
+			h, err = r.parent(h, n)
+		case op != '^':
+			err = fmt.Errorf("%s: invalid revision", rev)
+		}
+		if err != nil {
+			return h, fmt.Errorf("%s: %v", rev, err)
+		}
+		suffix = suffix[j:]
+	}
+	return h, nil
+}
+
+// name returns the object with the given name, that is a ref, or the name
+// of an object.
+func (r *repo) name(name string) (hash, error) {
+	for _, format := range []string{"%s", "refs/%s", "refs/tags/%s", "refs/heads/%s", "refs/remotes/%s", "refs/remotes/%s/HEAD"} {
+		if h, ok := r.ref(fmt.Sprintf(format, name)); ok {
+			return h, nil
+		}
+	}
+	if h, ok := parseHash(name); ok {
+		return h, nil
+	}
+	if _, err := hex.DecodeString(name + strings.Repeat("0", len(name)%2)); err == nil && len(name) >= 4 {
+		switch hs := r.db.prefix(strings.ToLower(name)); len(hs) {
+		case 1:
+			return hs[0], nil
+		case 0:
+		default:
+			return hash{}, fmt.Errorf("%s: ambiguous revision", name)
+		}
+	}
+	return hash{}, fmt.Errorf("%s: unknown revision", name)
+}
+
+// peel returns the commit that the object points to, through annotated tags.
+func (r *repo) peel(h hash) (hash, error) {
+	for i := 0; i < maxSymrefs; i++ {
+		typ, _, b, err := r.db.read(h, true, 0)
+		if err != nil {
+			return h, err
+		}
+		switch typ {
+		case "commit":
+			return h, nil
+		case "tag":
+			obj, ok := header(b, "object")
+			if h, ok = parseHash(obj); !ok {
+				return h, fmt.Errorf("tag %s: invalid object", h)
+			}
+		default:
+			return h, fmt.Errorf("object %s: is a %s, not a commit", h, typ)
+		}
+	}
+	return h, fmt.Errorf("object %s: too many tags", h)
+}
+
+// parent returns the n-th parent of the commit.
+func (r *repo) parent(h hash, n int) (hash, error) {
+	b, err := r.db.object(h, "commit")
+	if err != nil {
+		return h, err
+	}
+	parents := headers(b, "parent")
+	if n > len(parents) {
+		return h, fmt.Errorf("commit %s: has no parent %d", h, n)
+	}
+	p, ok := parseHash(parents[n-1])
+	if !ok {
+		return h, fmt.Errorf("commit %s: invalid parent", h)
+	}
+	return p, nil
+}
+
+// commit returns the tree of the commit, and the time it was committed.
+func (r *repo) commit(h hash) (hash, time.Time, error) {
+	b, err := r.db.object(h, "commit")
+	if err != nil {
+		return hash{}, time.Time{}, err
+	}
+	s, _ := header(b, "tree")
+	tree, ok := parseHash(s)
+	if !ok {
+		return tree, time.Time{}, fmt.Errorf("commit %s: invalid tree", h)
+	}
+	var t time.Time
+	// committer Name <email> 1136214245 +0000
+	if s, ok := header(b, "committer"); ok {
+		fields := strings.Fields(s[strings.LastIndex(s, ">")+1:])
+		if len(fields) > 0 {
+			sec, _ := strconv.ParseInt(fields[0], 10, 64)
+			t = time.Unix(sec, 0)
+		}
+	}
+	return tree, t, nil
+}
+
+// header returns the value of the first header with the given key, in a
+// commit or a tag object.
+func header(b []byte, key string) (string, bool) {
+	if vs := headers(b, key); len(vs) > 0 {
+		return vs[0], true
+	}
+	return "", false
+}
+
+// headers returns the values of the headers with the given key.
+func headers(b []byte, key string) (vs []string) {
+	for _, line := range strings.Split(string(b), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, key+" ") {
+			vs = append(vs, line[len(key)+1:])
+		}
+	}
+	return
+}
+
+// entry is an entry of a tree object.
+type entry struct {
+	mode uint32
+	name string
+	hash hash
+}
+
+// tree returns the entries of the tree object.
+func (r *repo) tree(h hash) ([]entry, error) {
+	b, err := r.db.object(h, "tree")
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	// <mode> <name>\x00<hash>
+	for len(b) > 0 {
+		sp := bytes.IndexByte(b, ' ')
+		nul := bytes.IndexByte(b, 0)
+		if sp == -1 || nul < sp || len(b) < nul+21 {
+			return nil, fmt.Errorf("tree %s: invalid entry", h)
+		}
+		mode, err := strconv.ParseUint(string(b[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("tree %s: invalid mode", h)
+		}
+		e := entry{mode: uint32(mode), name: string(b[sp+1 : nul])}
+		copy(e.hash[:], b[nul+1:nul+21])
+		entries = append(entries, e)
+		b = b[nul+21:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,340 @@
+package tree
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a pattern of a gitignore file.
+type ignoreRule struct {
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	// base is the directory the pattern is relative to, as a node path.
+	// For the files above the root, base is the root, and prefix is the
+	// path of the root relative to the directory of the file.
+	base   string
+	prefix string
+}
+
+// ignoreRules are the gitignore rules that apply to the entries of a
+// directory, from the lowest precedence to the highest.
+type ignoreRules []*ignoreRule
+
+// ignored reports whether the node is ignored by the gitignore rules of its
+// parent directory. The last rule that matches the node wins.
+func (node *Node) ignored(opts *Options) bool {
+	if filepath.Base(node.path) == ".git" {
+		return true
+	}
+	var isDir *bool
+	for i := len(node.ignore) - 1; i >= 0; i-- {
+		r := node.ignore[i]
+		if !r.match(node.path) {
+			continue
+		}
+		if r.dirOnly {
+			// The node is stat'ed only if a directory rule matches.
+			if isDir == nil {
+				fi := node.FileInfo
+				if fi == nil {
+					fi, _ = opts.Fs.Stat(node.path)
+				}
+				dir := fi != nil && fi.IsDir()
+				isDir = &dir
+			}
+			if !*isDir {
+				continue
+			}
+		}
+		return !r.negate
+	}
+	return false
+}
+
+// match reports whether the rule's pattern matches the given path.
+func (r *ignoreRule) match(p string) bool {
+	rel, err := filepath.Rel(r.base, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if r.prefix != "" {
+		rel = r.prefix + "/" + rel
+	}
+	if !r.anchored {
+		rel = path.Base(rel)
+	}
+	return r.re.MatchString(rel)
+}
+
+// gitIgnore returns the rules for the entries of the directory node, that
+// are the rules of its parent, and of its .gitignore file if it's one of
+// the given names. The root also gets the rules of the repository it's in.
+func (node *Node) gitIgnore(names []string, opts *Options) ignoreRules {
+	rules := node.ignore
+	if node.depth == 0 {
+		rules = repoIgnore(node.path, opts)
+	}
+	for _, name := range names {
+		if name == ".gitignore" {
+			if b, ok := readFile(opts.join(node.path, name), opts); ok {
+				// The rules of the parent are shared, and are not appended to.
+				rules = append(rules[:len(rules):len(rules)], parseIgnore(b, node.path, "")...)
+			}
+			break
+		}
+	}
+	return rules
+}
+
+// repoIgnore returns the rules of the repository that contains the root:
+// the global excludes file, .git/info/exclude and the .gitignore files
+// above the root, from the lowest precedence to the highest.
+func repoIgnore(root string, opts *Options) (rules ignoreRules) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	// The names of the directories from the repository to the root.
+	var elems []string
+	dir, found := root, false
+	for {
+		if _, err := opts.Fs.Stat(filepath.Join(dir, ".git")); err == nil {
+			found = true
+			break
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			break
+		}
+		elems = append([]string{filepath.Base(abs)}, elems...)
+		dir, abs = filepath.Join(dir, ".."), parent
+	}
+	if !found {
+		elems = nil
+	}
+	prefix := strings.Join(elems, "/")
+	if p := globalExcludes(); p != "" {
+		if b, err := ioutil.ReadFile(p); err == nil {
+			rules = append(rules, parseIgnore(string(b), root, prefix)...)
+		}
+	}
+	if !found {
+		return
+	}
+	gitDir := filepath.Join(dir, ".git")
+	// The .git file of worktrees and submodules has the path of the
+	// git directory.
+	if b, ok := readFile(gitDir, opts); ok && strings.HasPrefix(b, "gitdir:") {
+		if gitDir = strings.TrimSpace(strings.TrimPrefix(b, "gitdir:")); !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(dir, gitDir)
+		}
+	}
+	if b, ok := readFile(filepath.Join(gitDir, "info", "exclude"), opts); ok {
+		rules = append(rules, parseIgnore(b, root, prefix)...)
+	}
+	for i := range elems {
+		p := filepath.Join(dir, filepath.Join(elems[:i]...), ".gitignore")
+		if b, ok := readFile(p, opts); ok {
+			rules = append(rules, parseIgnore(b, root, strings.Join(elems[i:], "/"))...)
+		}
+	}
+	return
+}
+
+// readFile returns the content of the named file, if the Fs is an Opener.
+func readFile(name string, opts *Options) (string, bool) {
+	o, ok := opts.Fs.(Opener)
+	if !ok {
+		return "", false
+	}
+	rc, err := o.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	return string(b), err == nil
+}
+
+// globalExcludes returns the path of the global excludes file of git, that
+// is core.excludesFile in the user's git config, or git/ignore in the XDG
+// config directory.
+func globalExcludes() string {
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	var p string
+	for _, config := range []string{filepath.Join(xdg, "git", "config"), filepath.Join(home, ".gitconfig")} {
+		if v := configValue(config, "core", "excludesfile"); v != "" {
+			p = v
+		}
+	}
+	switch {
+	case strings.HasPrefix(p, "~/") && home != "":
+		return filepath.Join(home, p[2:])
+	case p != "":
+		return p
+	case xdg != "":
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	return ""
+}
+
+// configValue returns the value of the key in the section of a git config
+// file, or an empty string if it's not set.
+func configValue(name, section, key string) (value string) {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var inSection bool
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = strings.EqualFold(strings.Trim(line, "[] \t"), section)
+			continue
+		}
+		if k, v, ok := cut(line, "="); ok && inSection && strings.EqualFold(strings.TrimSpace(k), key) {
+			value = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return
+}
+
+// cut slices s around the first instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// parseIgnore returns the rules of a gitignore file. Invalid patterns are
+// skipped, as git does.
+func parseIgnore(content, base, prefix string) (rules ignoreRules) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// Trailing spaces are ignored, unless they are escaped.
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		r := &ignoreRule{base: base, prefix: prefix}
+		switch {
+		case line[0] == '!':
+			r.negate, line = true, line[1:]
+		case strings.HasPrefix(line, "\\!"), strings.HasPrefix(line, "\\#"):
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		r.anchored = strings.Contains(line, "/")
+		re, err := regexp.Compile(ignorePattern(strings.TrimPrefix(line, "/")))
+		if err != nil {
+			continue
+		}
+		r.re = re
+		rules = append(rules, r)
+	}
+	return
+}
+
+// ignorePattern returns the regular expression of a gitignore pattern. The
+// wildcards don't match slashes, except for "**" that matches any number
+// of directories.
+func ignorePattern(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			j := i + 1
+			for j < len(pattern) && pattern[j] == '*' {
+				j++
+			}
+			leading := i == 0 || pattern[i-1] == '/'
+			trailing := j == len(pattern) || pattern[j] == '/'
+			switch {
+			case j-i < 2 || !leading || !trailing:
+				b.WriteString("[^/]*")
+			case j == len(pattern):
+				b.WriteString(".*")
+			default:
+				// "**/" matches zero or more directories.
+				b.WriteString("(?:.*/)?")
+				j++
+			}
+			i = j - 1
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, n := ignoreClass(pattern[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// ignoreClass returns the regular expression of the bracket expression in
+// the beginning of s, and its length, or a zero length if it's not closed.
+func ignoreClass(s string) (string, int) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		b.WriteString("^/")
+		i++
+	}
+	for start := i; i < len(s); i++ {
+		switch {
+		case s[i] == ']' && i > start:
+			return b.String() + "]", i + 1
+		case strings.HasPrefix(s[i:], "[:"):
+			end := strings.Index(s[i+2:], ":]")
+			if end == -1 {
+				return "", 0
+			}
+			b.WriteString(s[i : i+end+4])
+			i += end + 3
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		case s[i] == '-':
+			b.WriteByte('-')
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	return "", 0
+}
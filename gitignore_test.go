@@ -0,0 +1,164 @@
+package tree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnorePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "a/b/c.log", false, true},
+		{"*.log", "a.log/b", false, false},
+		{"/a.txt", "a.txt", false, true},
+		{"/a.txt", "b/a.txt", false, false},
+		{"a/b", "a/b", false, true},
+		{"a/b", "c/a/b", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "a/build", true, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"**/foo", "foo", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**", "a/x/y", false, true},
+		{"a/*", "a/x/y", false, false},
+		{"?.go", "a.go", false, true},
+		{"?.go", "ab.go", false, false},
+		{"[abc].txt", "b.txt", false, true},
+		{"[!abc].txt", "b.txt", false, false},
+		{"[a-c].txt", "c.txt", false, true},
+		{"[[:digit:]].txt", "1.txt", false, true},
+		{"\\#file", "#file", false, true},
+		{"\\!file", "!file", false, true},
+		{"space\\ ", "space ", false, true},
+		{"trailing  ", "trailing", false, true},
+		{"a.(go)", "a.(go)", false, true},
+		{"[unclosed", "[unclosed", false, true},
+		{"# comment", "# comment", false, false},
+	}
+	for _, test := range tests {
+		fs := NewMemFs()
+		if test.isDir {
+			fs.Mkdir(filepath.Join("root", test.path), 0755)
+		} else {
+			fs.WriteFile(filepath.Join("root", test.path), nil, 0644)
+		}
+		node := &Node{path: filepath.Join("root", test.path), ignore: parseIgnore(test.pattern, "root", "")}
+		if ignored := node.ignored(&Options{Fs: fs}); ignored != test.ignored {
+			t.Errorf("%q matching %q: got %v, expected: %v", test.pattern, test.path, ignored, test.ignored)
+		}
+	}
+}
+
+// newGitFs returns the MemFs of a repository, with the ignore files:
+//
+//	repo/.git/info/exclude: secret
+//	repo/.gitignore:        *.log, !keep.log, build/, /vendor
+//	repo/src/.gitignore:    *.gen.go, !important.log
+func newGitFs(t *testing.T) *MemFs {
+	fs := NewMemFs()
+	for _, err := range []error{
+		fs.WriteFile("repo/.git/HEAD", []byte("ref: refs/heads/master\n"), 0644),
+		fs.WriteFile("repo/.git/info/exclude", []byte("# git ls-files --exclude-per-directory\nsecret\n"), 0644),
+		fs.WriteFile("repo/.gitignore", []byte("*.log\n!keep.log\nbuild/\n/vendor\n"), 0644),
+		fs.WriteFile("repo/a.log", nil, 0644),
+		fs.WriteFile("repo/keep.log", nil, 0644),
+		fs.WriteFile("repo/secret", nil, 0644),
+		fs.WriteFile("repo/build/out", nil, 0644),
+		fs.WriteFile("repo/vendor/lib.go", nil, 0644),
+		fs.WriteFile("repo/src/.gitignore", []byte("*.gen.go\n!important.log\n"), 0644),
+		fs.WriteFile("repo/src/main.go", nil, 0644),
+		fs.WriteFile("repo/src/main.gen.go", nil, 0644),
+		fs.WriteFile("repo/src/important.log", nil, 0644),
+		fs.WriteFile("repo/src/debug.log", nil, 0644),
+		fs.WriteFile("repo/src/build", nil, 0644),
+		fs.WriteFile("repo/src/vendor/lib.go", nil, 0644),
+		fs.WriteFile("repo/src/global.swp", nil, 0644),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+var gitIgnoreTests = []struct {
+	treeTest
+	root string
+}{
+	{treeTest{"root", &Options{GitIgnore: true, All: true}, `repo
+├── .gitignore
+├── keep.log
+└── src
+    ├── .gitignore
+    ├── build
+    ├── important.log
+    ├── main.go
+    └── vendor
+        └── lib.go
+`, 2, 7}, "repo"},
+	{treeTest{"subdir", &Options{GitIgnore: true}, `repo/src
+├── build
+├── important.log
+├── main.go
+└── vendor
+    └── lib.go
+`, 1, 4}, "repo/src"},
+	{treeTest{"ndjson", &Options{GitIgnore: true, NDJSON: true, NoReport: true}, `{"type":"directory","path":"repo/src","depth":0}
+{"type":"file","path":"repo/src/build","depth":1}
+{"type":"file","path":"repo/src/important.log","depth":1}
+{"type":"file","path":"repo/src/main.go","depth":1}
+{"type":"directory","path":"repo/src/vendor","depth":1}
+{"type":"file","path":"repo/src/vendor/lib.go","depth":2}
+`, 1, 4}, "repo/src"},
+	{treeTest{"disabled", &Options{DeepLevel: 1}, `repo
+├── a.log
+├── build
+├── keep.log
+├── secret
+├── src
+└── vendor
+`, 3, 3}, "repo"},
+}
+
+func TestGitIgnore(t *testing.T) {
+	// The global excludes file is in the XDG config directory.
+	dir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "git"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, "git", "ignore"), []byte("*.swp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Setenv(env, dir)
+	}
+	for _, test := range gitIgnoreTests {
+		test.opts.Fs = newGitFs(t)
+		test.opts.OutFile = out
+		inf := New(test.root)
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
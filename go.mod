@@ -1,3 +1,3 @@
 module github.com/a8m/tree
 
-go 1.15
+go 1.16
@@ -0,0 +1,109 @@
+package tree
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// htmlPrinter prints the tree as a standalone HTML page, like `tree -H` does.
+// Every entry links to BaseHREF followed by its path relative to the root,
+// and its name is colored with HTMLColor.
+type htmlPrinter struct{}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+ <meta http-equiv="Content-Type" content="text/html; charset=UTF-8">
+ <meta name="Author" content="Made by 'tree'">
+ <title>%s</title>
+ <style type="text/css">
+  BODY { font-family : monospace, sans-serif; color: black; }
+  P { font-family : monospace, sans-serif; color: black; margin: 0px; padding: 0px; }
+  A:visited { text-decoration : none; margin : 0px; padding : 0px; }
+  A:link { text-decoration : none; margin : 0px; padding : 0px; }
+  A:hover { text-decoration: underline; background-color : yellow; margin : 0px; padding : 0px; }
+  A:active { margin : 0px; padding : 0px; }
+  .exec { color: green; font-weight: bold; }
+  .archive { color: red; font-weight: bold; }
+  .media { color: magenta; font-weight: bold; }
+  .dir { color: blue; font-weight: bold; }
+  .fifo { color: olive; background-color: black; }
+  .socket { color: magenta; background-color: black; font-weight: bold; }
+  .device { color: yellow; background-color: black; font-weight: bold; }
+  .orphan { color: red; background-color: black; font-weight: bold; }
+  .link { color: teal; font-weight: bold; }
+ </style>
+</head>
+<body>
+	<h1>%s</h1><p>
+`
+
+func (htmlPrinter) begin(opts *Options) {
+	title := opts.Title
+	if title == "" {
+		title = "Directory Tree"
+	}
+	title = html.EscapeString(title)
+	fmt.Fprintf(opts.OutFile, htmlHeader, title, title)
+}
+
+func (htmlPrinter) print(node *Node, i int, opts *Options) {
+	fmt.Fprint(opts.OutFile, "\t")
+	node.printHTML("", "", opts)
+}
+
+func (htmlPrinter) end(dirs, files int, opts *Options) {
+	w := opts.OutFile
+	fmt.Fprintln(w, "\t</p>")
+	if !opts.NoReport {
+		fmt.Fprintf(w, "\t<br><br><p>\n\n%s\n\t</p>\n", report(dirs, files, opts))
+	}
+	fmt.Fprintln(w, "</body>\n</html>")
+}
+
+// printHTML prints the node as a line of the HTML page. rel is the node path
+// relative to the root, used to build its link.
+func (node *Node) printHTML(indent, rel string, opts *Options) {
+	w := opts.OutFile
+	name := html.EscapeString(node.name(opts))
+	if node.err != nil {
+		fmt.Fprintf(w, "%s [%s]<br>\n", name, html.EscapeString(node.errMsg()))
+		return
+	}
+	if props := node.props(opts); len(props) > 0 {
+		props := html.EscapeString(strings.Join(props, " "))
+		fmt.Fprintf(w, "[%s]&nbsp;&nbsp;", strings.Replace(props, " ", "&nbsp;", -1))
+	}
+	if opts.Quotes {
+		name = fmt.Sprintf("&quot;%s&quot;", name)
+	}
+	href := html.EscapeString(node.href(rel, opts))
+	fmt.Fprintf(w, `<a href="%s">%s</a>`, href, HTMLColor(node, name))
+	if node.Mode()&os.ModeSymlink == os.ModeSymlink {
+		target, _, recursive := node.link(opts)
+		fmt.Fprintf(w, " -&gt; %s", html.EscapeString(target))
+		if recursive {
+			fmt.Fprint(w, " [recursive, not followed]")
+		}
+	}
+	fmt.Fprintln(w, "<br>")
+	add := "│&nbsp;&nbsp;&nbsp;"
+	for i, nnode := range node.nodes {
+		fmt.Fprint(w, "\t")
+		if opts.NoIndent {
+			add = ""
+		} else {
+			if i == len(node.nodes)-1 {
+				fmt.Fprint(w, indent+"└──&nbsp;")
+				add = "&nbsp;&nbsp;&nbsp;&nbsp;"
+			} else {
+				fmt.Fprint(w, indent+"├──&nbsp;")
+			}
+		}
+		nnode.printHTML(indent+add, path.Join(rel, filepath.Base(nnode.path)), opts)
+	}
+}
@@ -0,0 +1,60 @@
+package tree
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+var htmlTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, HTML: true, BaseHREF: "http://host/pub/"}, `	<a href="http://host/pub/"><span class="dir">root</span></a><br>
+	├──&nbsp;<a href="http://host/pub/a%20&amp;%20b.tar"><span class="archive">a &amp; b.tar</span></a><br>
+	├──&nbsp;<a href="http://host/pub/c/"><span class="dir">c</span></a><br>
+	│&nbsp;&nbsp;&nbsp;└──&nbsp;<a href="http://host/pub/c/%3Cd%3E">&lt;d&gt;</a><br>
+	└──&nbsp;bad [stat failed]<br>
+	</p>
+	<br><br><p>
+
+1 directories, 2 files
+	</p>
+</body>
+</html>
+`, 1, 2},
+	{"relative + size + noreport", &Options{Fs: fs, OutFile: out, HTML: true, ByteSize: true, NoReport: true}, `	[&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;150]&nbsp;&nbsp;<a href="./"><span class="dir">root</span></a><br>
+	├──&nbsp;[&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;100]&nbsp;&nbsp;<a href="a%20&amp;%20b.tar"><span class="archive">a &amp; b.tar</span></a><br>
+	├──&nbsp;[&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;50]&nbsp;&nbsp;<a href="c/"><span class="dir">c</span></a><br>
+	│&nbsp;&nbsp;&nbsp;└──&nbsp;[&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;&nbsp;50]&nbsp;&nbsp;<a href="c/%3Cd%3E">&lt;d&gt;</a><br>
+	└──&nbsp;bad [stat failed]<br>
+	</p>
+</body>
+</html>
+`, 1, 2},
+}
+
+func TestHTML(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir,
+		files: []*file{
+			{name: "a & b.tar", size: 100},
+			{name: "bad"}, // stat fails on this file
+			{name: "c", mode: os.ModeDir, files: []*file{{name: "<d>", size: 50}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range htmlTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !strings.HasPrefix(out.str, "<!DOCTYPE html>") || !strings.Contains(out.str, "<title>Directory Tree</title>") {
+			t.Errorf("%s: missing HTML header:\n%s", test.name, out.str)
+		}
+		body := out.str[strings.Index(out.str, "<h1>Directory Tree</h1><p>\n")+27:]
+		if body != test.expected {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, body, test.expected)
+		}
+		out.clear()
+	}
+}
@@ -0,0 +1,307 @@
+// Package httpfs implements tree.Fs over the directory listings of an HTTP
+// server, e.g: the autoindex pages of Apache and nginx.
+package httpfs
+
+import (
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is a tree.Fs of the directory listings under a URL. The listings are
+// fetched when they are first read, and their responses are cached. The
+// size and the time of the files are parsed from the listings, and they
+// are zero if they are missing, e.g: the size of directories.
+type FS struct {
+	// Root is the URL of the root directory, e.g: "https://mirror/pub/".
+	Root string
+	// Client is used for the requests, and defaults to http.DefaultClient.
+	Client *http.Client
+	mu     sync.Mutex
+	cache  map[string]*listing
+}
+
+// listing is the response of a directory listing.
+type listing struct {
+	entries []*entry
+	err     error
+}
+
+// entry is a link of a listing.
+type entry struct {
+	name    string
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// New returns the FS of the listings under the given URL.
+func New(root string) *FS {
+	return &FS{Root: root, cache: make(map[string]*listing)}
+}
+
+// Join returns the URL of the named entry of the directory. Unlike
+// filepath.Join, it keeps the "//" of the scheme, so the paths of the
+// tree, e.g: with -f, are the URLs of the files.
+func (fs *FS) Join(dir, name string) string {
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+// rel returns the slash-separated path of the named file, relative to the
+// root.
+func (fs *FS) rel(op, name string) (string, error) {
+	root, p := strings.TrimSuffix(fs.Root, "/"), strings.TrimSuffix(name, "/")
+	if p == root {
+		return ".", nil
+	}
+	if strings.HasPrefix(p, root+"/") {
+		if rel := path.Clean(p[len(root)+1:]); rel != ".." && !strings.HasPrefix(rel, "../") {
+			return rel, nil
+		}
+	}
+	return "", &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// url returns the URL of the file at rel, that ends with a slash if it's a
+// directory.
+func (fs *FS) url(rel string, dir bool) string {
+	u := strings.TrimSuffix(fs.Root, "/")
+	if rel != "." {
+		for _, elem := range strings.Split(rel, "/") {
+			u += "/" + url.PathEscape(elem)
+		}
+	}
+	if dir {
+		u += "/"
+	}
+	return u
+}
+
+// Stat returns the FileInfo of the named file, from the listing of its
+// directory.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	rel, err := fs.rel("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		return &fileInfo{&entry{name: filepath.Base(name), dir: true}}, nil
+	}
+	entries, err := fs.list(path.Dir(rel))
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	for _, e := range entries {
+		if e.name == path.Base(rel) {
+			return &fileInfo{e}, nil
+		}
+	}
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// ReadDir returns the names of the entries of the named directory, in the
+// order of its listing.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	rel, err := fs.rel("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.list(rel)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names, nil
+}
+
+// Open requests the content of the named file.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	rel, err := fs.rel("open", name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fs.get(fs.url(rel, false))
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return resp.Body, nil
+}
+
+// list returns the entries of the directory at rel, from the cache if it
+// was already requested.
+func (fs *FS) list(rel string) ([]*entry, error) {
+	fs.mu.Lock()
+	l, ok := fs.cache[rel]
+	fs.mu.Unlock()
+	if ok {
+		return l.entries, l.err
+	}
+	l = new(listing)
+	resp, err := fs.get(fs.url(rel, true))
+	if err != nil {
+		l.err = err
+	} else {
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			l.err = err
+		} else {
+			l.entries = parse(resp.Request.URL, string(b))
+		}
+	}
+	fs.mu.Lock()
+	if fs.cache == nil {
+		fs.cache = make(map[string]*listing)
+	}
+	fs.cache[rel] = l
+	fs.mu.Unlock()
+	return l.entries, l.err
+}
+
+// get requests the given URL, and returns an error if the response is not
+// successful.
+func (fs *FS) get(u string) (*http.Response, error) {
+	client := fs.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+var (
+	linkRe = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>.*?</a>`)
+	tagRe  = regexp.MustCompile(`<[^>]*>`)
+	dateRe = regexp.MustCompile(`\b(\d{2}-[A-Za-z]{3}-\d{4}|\d{4}-\d{2}-\d{2}) (\d{2}:\d{2}(:\d{2})?)\b`)
+	sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)([KMGTP]?)$`)
+)
+
+// The formats of the dates of the listings.
+var dateFormats = []string{
+	"02-Jan-2006 15:04", "02-Jan-2006 15:04:05",
+	"2006-01-02 15:04", "2006-01-02 15:04:05",
+}
+
+// parse returns the entries of the listing of the directory at the given
+// URL. The entries are the links to the files of the directory, and their
+// size and time are parsed from the text that follows them, until the next
+// link or line.
+func parse(dir *url.URL, page string) (entries []*entry) {
+	seen := make(map[string]bool)
+	links := linkRe.FindAllStringSubmatchIndex(page, -1)
+	for i, m := range links {
+		e := child(dir, html.UnescapeString(page[m[2]:m[3]]))
+		if e == nil || seen[e.name] {
+			continue
+		}
+		seen[e.name] = true
+		end := len(page)
+		if i+1 < len(links) {
+			end = links[i+1][0]
+		}
+		text := page[m[1]:end]
+		if j := strings.IndexAny(text, "\r\n"); j != -1 {
+			text = text[:j]
+		}
+		text = tagRe.ReplaceAllString(text, " ")
+		if d := dateRe.FindStringSubmatch(text); d != nil {
+			for _, format := range dateFormats {
+				if t, err := time.Parse(format, d[1]+" "+d[2]); err == nil {
+					e.modTime = t
+					break
+				}
+			}
+			text = strings.Replace(text, d[0], " ", 1)
+		}
+		if !e.dir {
+			for _, field := range strings.Fields(text) {
+				if size, ok := parseSize(field); ok {
+					e.size = size
+					break
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return
+}
+
+// child returns the entry of the link, if it's a file of the directory at
+// the given URL. Links to other directories and sort links are skipped.
+func child(dir *url.URL, href string) *entry {
+	u, err := dir.Parse(href)
+	if err != nil || u.Scheme != dir.Scheme || u.Host != dir.Host || u.RawQuery != "" {
+		return nil
+	}
+	prefix := dir.Path
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	name := strings.TrimPrefix(u.Path, prefix)
+	if name == u.Path || name == "" {
+		return nil
+	}
+	e := &entry{name: strings.TrimSuffix(name, "/")}
+	e.dir = e.name != name
+	if e.name == "" || e.name == "." || e.name == ".." || strings.Contains(e.name, "/") {
+		return nil
+	}
+	return e
+}
+
+// parseSize parses the size of a listing, e.g: "1234" or "1.5M", where the
+// units are powers of 1024.
+func parseSize(s string) (int64, bool) {
+	m := sizeRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	if m[2] != "" {
+		n *= math.Pow(1024, float64(strings.Index("KMGTP", m[2])+1))
+	}
+	return int64(n), true
+}
+
+// fileInfo is the FileInfo of an entry.
+type fileInfo struct {
+	e *entry
+}
+
+func (fi *fileInfo) Name() string       { return fi.e.name }
+func (fi *fileInfo) Size() int64        { return fi.e.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.e.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.e.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.e.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,158 @@
+package httpfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+// The listings of the test server, in the formats of Apache (fancy and
+// plain) and nginx.
+var pages = map[string]string{
+	"/pub/": `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">
+<html><head><title>Index of /pub</title></head><body>
+<h1>Index of /pub</h1>
+<table>
+<tr><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=M;O=A">Last modified</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+<tr><td><a href="/">Parent Directory</a></td><td>&nbsp;</td><td align="right">  - </td></tr>
+<tr><td><a href="README.txt">README.txt</a></td><td align="right">2024-01-02 10:11  </td><td align="right">1.5K</td></tr>
+<tr><td><a href="dists/">dists/</a></td><td align="right">2024-01-03 12:00  </td><td align="right">  - </td></tr>
+<tr><td><a href="pool/">pool/</a></td><td align="right">2023-12-31 23:59  </td><td align="right">  - </td></tr>
+<tr><td><a href="private/">private/</a></td><td align="right">2023-12-31 23:59  </td><td align="right">  - </td></tr>
+<tr><td><a href="https://example.com/">Mirror list</a></td></tr>
+</table>
+</body></html>
+`,
+	"/pub/dists/": `<html>
+<head><title>Index of /pub/dists/</title></head>
+<body>
+<h1>Index of /pub/dists/</h1><hr><pre><a href="../">../</a>
+<a href="stable/">stable/</a>                                            03-Jan-2024 12:00                   -
+<a href="a%20b.tar.gz">a b.tar.gz</a>                                      03-Jan-2024 11:00             2048000
+<a href="very-long-name-that-nginx-trunc..&gt;">very-long-name-that-nginx-trunc..&gt;</a> 03-Jan-2024 11:00      10
+</pre><hr></body>
+</html>
+`,
+	"/pub/dists/stable/": `<html><body><pre><a href="/pub/dists/">Parent Directory</a>
+<a href="Release">Release</a>      02-Jan-2024 09:00  3M
+<a href="Release.gpg">Release.gpg</a>  02-Jan-2024 09:00  833
+</pre></body></html>
+`,
+	"/pub/pool/": `<html><body><pre><a href="../">../</a>
+</pre></body></html>
+`,
+}
+
+// server returns a test server of the listings, and the number of requests
+// it got for each path.
+func server(t *testing.T) (*httptest.Server, map[string]int) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/pub/README.txt" {
+			w.Write([]byte("readme"))
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, requests
+}
+
+var tests = []struct {
+	name     string
+	opts     *tree.Options
+	expected string
+}{
+	{"basic", &tree.Options{}, `
+├── README.txt
+├── dists
+│   ├── a b.tar.gz
+│   ├── stable
+│   │   ├── Release
+│   │   └── Release.gpg
+│   └── very-long-name-that-nginx-trunc..>
+├── pool
+└── private [403 Forbidden]
+`},
+	{"level", &tree.Options{DeepLevel: 1}, `
+├── README.txt
+├── dists
+├── pool
+└── private
+`},
+	{"sizes", &tree.Options{ByteSize: true, LastMod: true, Pattern: "Release*|*.tar.gz", Prune: true, Now: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}, `
+├── [    5194561]  dists
+│   ├── [    2048000 Jan 03 11:00]  a b.tar.gz
+│   └── [    3146561]  stable
+│       ├── [    3145728 Jan 02 09:00]  Release
+│       └── [        833 Jan 02 09:00]  Release.gpg
+└── private [403 Forbidden]
+`},
+	{"full path", &tree.Options{FullPath: true, Pattern: "Release", Prune: true}, `
+├── http://host/pub/dists
+│   └── http://host/pub/dists/stable
+│       └── http://host/pub/dists/stable/Release
+└── http://host/pub/private [403 Forbidden]
+`},
+}
+
+func TestFS(t *testing.T) {
+	for _, test := range tests {
+		ts, requests := server(t)
+		root := ts.URL + "/pub/"
+		b := new(bytes.Buffer)
+		test.opts.Fs = New(root)
+		test.opts.OutFile = b
+		inf := tree.New(root)
+		inf.Visit(test.opts)
+		inf.Print(test.opts)
+		// The root line is left out, and the other paths are written with
+		// another host, as they have the port of the server.
+		actual := strings.Replace(b.String()[bytes.IndexByte(b.Bytes(), '\n'):], ts.URL, "http://host", -1)
+		if actual != test.expected {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, actual, test.expected)
+		}
+		// The listings are requested once.
+		for path, n := range requests {
+			if n != 1 {
+				t.Errorf("%s: %s was requested %d times", test.name, path, n)
+			}
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	ts, _ := server(t)
+	fs := New(ts.URL + "/pub")
+	rc, err := fs.Open(ts.URL + "/pub/README.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "readme" {
+		t.Errorf("got: %q, expected: %q", b, "readme")
+	}
+	fi, err := fs.Stat(ts.URL + "/pub/README.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 1536 || !fi.ModTime().Equal(time.Date(2024, 1, 2, 10, 11, 0, 0, time.UTC)) {
+		t.Errorf("got size %d and time %v", fi.Size(), fi.ModTime())
+	}
+}
@@ -0,0 +1,282 @@
+// Package imagefs implements tree.Fs over the root file system of a
+// container image, that is read from a docker save archive or from an OCI
+// image layout directory.
+package imagefs
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/a8m/tree"
+	"github.com/a8m/tree/internal/vpath"
+	"github.com/a8m/tree/tarfs"
+)
+
+// ErrNotImage is returned by Open for archives and directories that are not
+// images.
+var ErrNotImage = errors.New("not a docker save archive or an OCI image layout")
+
+// FS is a tree.Fs of the root file system of an image. It's an OverlayFs of
+// its layers, where the layers are named by their index from the lowest
+// one, e.g: "0". The Sys method of the FileInfo returns the *tarfs.Header
+// of the entry in its layer.
+type FS struct {
+	tree.OverlayFs
+}
+
+// Open indexes the layers of the image at the given path, that is a docker
+// save archive or an OCI image layout directory, or an archive of one. The
+// path is the root of the FS. The first image of the archive is used, and
+// the manifest of the current platform is preferred in image indexes.
+func Open(path string) (*FS, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var s store = dirStore(path)
+	if !fi.IsDir() {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		s = &tarStore{f}
+	}
+	names, err := layers(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	fss, err := s.index(names)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	fs := &FS{tree.OverlayFs{Root: path}}
+	for i, lfs := range fss {
+		fs.Layers = append(fs.Layers, tree.Layer{Name: strconv.Itoa(i), Fs: lfs, Root: "."})
+	}
+	return fs, nil
+}
+
+// Layer returns the FS of the n'th layer, rooted at the root of the image.
+// It lists what the layer adds, including its whiteout markers.
+func (fs *FS) Layer(n int) (*tarfs.FS, error) {
+	if n < 0 || n >= len(fs.Layers) {
+		return nil, fmt.Errorf("layer %d is out of range, the image has %d layers", n, len(fs.Layers))
+	}
+	lfs := *fs.Layers[n].Fs.(*tarfs.FS)
+	lfs.Root = fs.Root
+	return &lfs, nil
+}
+
+// store reads the files of an image.
+type store interface {
+	// readFile returns the content of the named file.
+	readFile(name string) ([]byte, error)
+	// index returns the FS of the named layers, in the same order.
+	index(names []string) ([]*tarfs.FS, error)
+}
+
+// descriptor is a reference to a blob in an OCI index or manifest.
+type descriptor struct {
+	Digest   string `json:"digest"`
+	Platform *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// layers returns the paths of the layer blobs of the image, from the
+// lowest one.
+func layers(s store) ([]string, error) {
+	// docker save writes a manifest.json, also when it writes an OCI layout.
+	if b, err := s.readFile("manifest.json"); err == nil {
+		var manifests []struct{ Layers []string }
+		if err := json.Unmarshal(b, &manifests); err == nil && len(manifests) > 0 {
+			return manifests[0].Layers, nil
+		}
+	}
+	if _, err := s.readFile("oci-layout"); err != nil {
+		return nil, ErrNotImage
+	}
+	b, err := s.readFile("index.json")
+	if err != nil {
+		return nil, err
+	}
+	// Indexes are followed until an image manifest.
+	for {
+		var m struct {
+			Manifests []descriptor `json:"manifests"`
+			Layers    []descriptor `json:"layers"`
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("index: %v", err)
+		}
+		if len(m.Manifests) == 0 {
+			var names []string
+			for _, l := range m.Layers {
+				name, err := blob(l.Digest)
+				if err != nil {
+					return nil, err
+				}
+				names = append(names, name)
+			}
+			return names, nil
+		}
+		d := m.Manifests[0]
+		for _, m := range m.Manifests {
+			if p := m.Platform; p != nil && p.OS == runtime.GOOS && p.Architecture == runtime.GOARCH {
+				d = m
+				break
+			}
+		}
+		name, err := blob(d.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = s.readFile(name); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// blob returns the path of the blob with the given digest, e.g:
+// "blobs/sha256/<hex>".
+func blob(digest string) (string, error) {
+	i := strings.IndexByte(digest, ':')
+	if i <= 0 || strings.ContainsAny(digest, "/\\") || strings.Contains(digest, "..") {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return "blobs/" + digest[:i] + "/" + digest[i+1:], nil
+}
+
+// dirStore is an image layout directory.
+type dirStore string
+
+// path returns the path of the named file, that can't be out of the
+// directory.
+func (s dirStore) path(name string) string {
+	return filepath.Join(string(s), filepath.FromSlash(path.Clean("/"+name)))
+}
+
+func (s dirStore) readFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(s.path(name))
+}
+
+func (s dirStore) index(names []string) ([]*tarfs.FS, error) {
+	fss := make([]*tarfs.FS, len(names))
+	for i, name := range names {
+		f, err := os.Open(s.path(name))
+		if err != nil {
+			return nil, err
+		}
+		fss[i], err = tarfs.New(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return fss, nil
+}
+
+// tarStore is an archive of an image. Its entries are looked up by reading
+// the archive from the start, which is cheap as their content is skipped.
+type tarStore struct {
+	f io.ReadSeeker
+}
+
+// walk calls fn with the clean name of every regular file and symbolic
+// link, until it returns false.
+func (s *tarStore) walk(fn func(name string, hdr *tar.Header, r io.Reader) (bool, error)) error {
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	tr := tar.NewReader(s.f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeSymlink {
+			continue
+		}
+		if more, err := fn(vpath.Clean(hdr.Name), hdr, tr); !more || err != nil {
+			return err
+		}
+	}
+}
+
+func (s *tarStore) readFile(name string) (b []byte, err error) {
+	err = os.ErrNotExist
+	walkErr := s.walk(func(n string, hdr *tar.Header, r io.Reader) (bool, error) {
+		if n != name || hdr.Typeflag != tar.TypeReg {
+			return true, nil
+		}
+		b, err = ioutil.ReadAll(r)
+		return false, nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	return b, err
+}
+
+func (s *tarStore) index(names []string) ([]*tarfs.FS, error) {
+	fss := make(map[string]*tarfs.FS)
+	for _, name := range names {
+		fss[name] = nil
+	}
+	// Older versions of docker save link the layers that are repeated, and
+	// the archive is read again if their targets were skipped.
+	links := make(map[string]string)
+	for again := true; again; {
+		err := s.walk(func(name string, hdr *tar.Header, r io.Reader) (bool, error) {
+			if hdr.Typeflag == tar.TypeSymlink {
+				links[name] = vpath.Clean(path.Join(path.Dir(name), hdr.Linkname))
+				return true, nil
+			}
+			if fs, ok := fss[name]; !ok || fs != nil {
+				return true, nil
+			}
+			fs, err := tarfs.New(r)
+			if err != nil {
+				return false, fmt.Errorf("%s: %v", name, err)
+			}
+			fss[name] = fs
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		again = false
+		for _, name := range names {
+			if target, ok := links[name]; ok {
+				if _, ok := fss[target]; !ok {
+					fss[target], again = nil, true
+				}
+			}
+		}
+	}
+	layers := make([]*tarfs.FS, len(names))
+	for i, name := range names {
+		if target, ok := links[name]; ok {
+			name = target
+		}
+		if layers[i] = fss[name]; layers[i] == nil {
+			return nil, fmt.Errorf("%s: layer not found", name)
+		}
+	}
+	return layers, nil
+}
@@ -0,0 +1,211 @@
+package imagefs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+var mtime = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+// entry is a file of an archive, that is a directory if its name ends with
+// a slash, or a symbolic link if it has a target.
+type entry struct {
+	name, content, target string
+}
+
+// archive returns a tar archive of the given entries.
+func archive(t *testing.T, entries ...entry) []byte {
+	b := new(bytes.Buffer)
+	w := tar.NewWriter(b)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), ModTime: mtime}
+		switch {
+		case e.target != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Mode = tar.TypeSymlink, e.target, 0777
+		case e.name[len(e.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		default:
+			hdr.Typeflag = tar.TypeReg
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// The layers of the image of the tests.
+var layerEntries = [][]entry{
+	{
+		{name: "bin/"},
+		{name: "bin/sh", content: "shell"},
+		{name: "etc/passwd", content: "root"},
+		{name: "etc/motd", content: "hello"},
+		{name: "var/cache/apk/index", content: "packages"},
+	},
+	{
+		{name: "etc/.wh.motd"},
+		{name: "var/cache/.wh..wh..opq"},
+		{name: "var/cache/app", content: "app"},
+		{name: "app/"},
+		{name: "app/main", content: "binary"},
+		{name: "app/sh", target: "/bin/sh"},
+	},
+}
+
+func layerTars(t *testing.T) [][]byte {
+	var layers [][]byte
+	for _, entries := range layerEntries {
+		layers = append(layers, archive(t, entries...))
+	}
+	return layers
+}
+
+// dockerSave writes a docker save archive of the test image, and returns
+// its path.
+func dockerSave(t *testing.T) string {
+	layers := layerTars(t)
+	p := filepath.Join(t.TempDir(), "image.tar")
+	b := archive(t,
+		entry{name: "1111/layer.tar", content: string(layers[0])},
+		entry{name: "2222/layer.tar", content: string(layers[1])},
+		entry{name: "manifest.json", content: `[{"Config":"config.json","RepoTags":["app:latest"],` +
+			`"Layers":["1111/layer.tar","2222/layer.tar"]}]`},
+	)
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// ociLayout writes an OCI image layout of the test image, with an index
+// and gzip compressed layers, and returns its path.
+func ociLayout(t *testing.T) string {
+	dir := filepath.Join(t.TempDir(), "layout")
+	write := func(name string, b []byte) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blob := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		digest := hex.EncodeToString(sum[:])
+		write("blobs/sha256/"+digest, b)
+		return `{"digest":"sha256:` + digest + `"}`
+	}
+	manifest := `{"layers":[`
+	for i, layer := range layerTars(t) {
+		gz := new(bytes.Buffer)
+		w := gzip.NewWriter(gz)
+		w.Write(layer)
+		w.Close()
+		if i > 0 {
+			manifest += ","
+		}
+		manifest += blob(gz.Bytes())
+	}
+	manifest = blob([]byte(manifest + `]}`))
+	write("oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`))
+	write("index.json", []byte(`{"manifests":[`+blob([]byte(`{"manifests":[`+manifest+`]}`))+`]}`))
+	return dir
+}
+
+var tests = []struct {
+	name     string
+	layer    int
+	opts     *tree.Options
+	expected string
+}{
+	{"rootfs", -1, &tree.Options{}, `
+├── app
+│   ├── main
+│   └── sh -> /bin/sh
+├── bin
+│   └── sh
+├── etc
+│   └── passwd
+└── var
+    └── cache
+        └── app
+`},
+	{"show-layer", -1, &tree.Options{ShowLayer: true, DeepLevel: 1}, `
+├── app [layer: 1]
+├── bin [layer: 0]
+├── etc [layer: 1]
+└── var [layer: 1]
+`},
+	{"layer", 1, &tree.Options{All: true, ByteSize: true}, `
+├── [          6]  app
+│   ├── [          6]  main
+│   └── [          0]  sh -> /bin/sh
+├── [          0]  etc
+│   └── [          0]  .wh.motd
+└── [          3]  var
+    └── [          3]  cache
+        ├── [          0]  .wh..wh..opq
+        └── [          3]  app
+`},
+}
+
+func TestImage(t *testing.T) {
+	for _, root := range []string{dockerSave(t), ociLayout(t)} {
+		for _, test := range tests {
+			img, err := Open(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.opts.Fs = img
+			if test.layer >= 0 {
+				if test.opts.Fs, err = img.Layer(test.layer); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b := new(bytes.Buffer)
+			test.opts.OutFile = b
+			inf := tree.New(root)
+			inf.Visit(test.opts)
+			inf.Print(test.opts)
+			// The root line is left out, as it's a temporary path.
+			actual := b.String()
+			actual = actual[bytes.IndexByte(b.Bytes(), '\n'):]
+			if actual != test.expected {
+				t.Errorf("%s: %s:\ngot:\n%+v\nexpected:\n%+v", filepath.Base(root), test.name, actual, test.expected)
+			}
+		}
+	}
+}
+
+func TestNotImage(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "files.tar")
+	b := archive(t, entry{name: "manifest.json", content: `{"name":"not an image"}`})
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(p); !errors.Is(err, ErrNotImage) {
+		t.Errorf("expected ErrNotImage, got: %v", err)
+	}
+	img, err := Open(dockerSave(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := img.Layer(2); err == nil {
+		t.Error("expected error for a missing layer")
+	}
+}
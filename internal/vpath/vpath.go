@@ -0,0 +1,153 @@
+// Package vpath implements the path index of archives, and the evaluation
+// of symbolic links, that are shared by the file systems of tree.
+// The paths are slash-separated, and relative to the root of the file
+// system, that is ".".
+package vpath
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxLinks is the maximum number of links that are followed when a path is
+// resolved.
+const MaxLinks = 255
+
+// ErrTooManyLinks is returned by Eval when MaxLinks is reached.
+var ErrTooManyLinks = errors.New("too many links")
+
+// Clean returns the path of an entry name, e.g: "b" for "./a/../b/", or
+// "." for the root. Leading slashes and ".." elements are ignored.
+func Clean(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// Rel returns the slash-separated path of name relative to root.
+func Rel(root, name string) (string, error) {
+	rel, err := filepath.Rel(root, name)
+	return filepath.ToSlash(rel), err
+}
+
+// Index indexes the entries of an archive by their path. The parent
+// directories that are not in the archive are implied.
+type Index struct {
+	entries map[string]*entry
+}
+
+// entry is an indexed entry, and the names of its entries if it's a
+// directory.
+type entry struct {
+	v     interface{}
+	names []string
+}
+
+// NewIndex returns an index that has the root directory, whose entry is v.
+func NewIndex(v interface{}) *Index {
+	return &Index{entries: map[string]*entry{".": {v: v}}}
+}
+
+// Add adds the entry v at the clean path name. It replaces the entry that
+// has the same path, as it is when the archive is extracted. The missing
+// parent directories are added with the entries that dir returns.
+func (x *Index) Add(name string, v interface{}, dir func(name string) interface{}) {
+	if e, ok := x.entries[name]; ok {
+		e.v = v
+		return
+	}
+	parent := path.Dir(name)
+	if _, ok := x.entries[parent]; !ok {
+		x.Add(parent, dir(parent), dir)
+	}
+	x.entries[name] = &entry{v: v}
+	x.entries[parent].names = append(x.entries[parent].names, path.Base(name))
+}
+
+// Get returns the entry at the given path.
+func (x *Index) Get(name string) (v interface{}, ok bool) {
+	e, ok := x.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return e.v, true
+}
+
+// Lookup returns the path of the named file relative to root, and its
+// entry. The error of the operation op is returned if it's not indexed.
+func (x *Index) Lookup(op, root, name string) (rel string, v interface{}, err error) {
+	if rel, err = Rel(root, name); err == nil {
+		if e, ok := x.entries[rel]; ok {
+			return rel, e.v, nil
+		}
+	}
+	return "", nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// Names returns a copy of the names of the entries of the directory at
+// the given path, in the order they were added.
+func (x *Index) Names(name string) []string {
+	if e, ok := x.entries[name]; ok {
+		return append([]string(nil), e.names...)
+	}
+	return nil
+}
+
+// Resolver evaluates the symbolic links of a file system.
+type Resolver struct {
+	// Readlink returns the target of the link at the given path, and false
+	// if it's not a link, or if it's missing. Relative targets are
+	// relative to the directory of the link.
+	Readlink func(p string) (target string, ok bool, err error)
+	// Chroot resolves absolute paths from the root, and the parent of the
+	// root to the root itself, like in an archive. Otherwise, they are
+	// resolved from "/".
+	Chroot bool
+}
+
+// Eval returns the path of p after the evaluation of its symbolic links,
+// like filepath.EvalSymlinks. Missing files are resolved as is.
+func (r Resolver) Eval(p string) (string, error) {
+	root := "/"
+	if r.Chroot {
+		root = "."
+	}
+	resolved := "."
+	if path.IsAbs(p) {
+		resolved = root
+	}
+	rest := strings.Split(p, "/")
+	for links := 0; len(rest) > 0; {
+		elem := rest[0]
+		rest = rest[1:]
+		switch {
+		case elem == "" || elem == ".":
+			continue
+		case elem == ".." && r.Chroot:
+			resolved = path.Dir(resolved)
+			continue
+		}
+		next := path.Join(resolved, elem)
+		target, ok, err := r.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			resolved = next
+			continue
+		}
+		if links++; links > MaxLinks {
+			return "", ErrTooManyLinks
+		}
+		if path.IsAbs(target) {
+			resolved = root
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,86 @@
+package vpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	for name, expected := range map[string]string{
+		"":          ".",
+		"./":        ".",
+		"/":         ".",
+		"a/":        "a",
+		"./a/../b/": "b",
+		"../../a":   "a",
+		"/a//b":     "a/b",
+	} {
+		if actual := Clean(name); actual != expected {
+			t.Errorf("%q: got %q, expected: %q", name, actual, expected)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	x := NewIndex("root")
+	implied := func(name string) interface{} { return "implied " + name }
+	x.Add("a/b/c", "c", implied)
+	x.Add("a/d", "d", implied)
+	x.Add("a", "a", implied)
+	for name, expected := range map[string]string{".": "root", "a": "a", "a/b": "implied a/b", "a/b/c": "c"} {
+		if v, ok := x.Get(name); !ok || v != expected {
+			t.Errorf("%q: got %v, expected: %q", name, v, expected)
+		}
+	}
+	if names := strings.Join(x.Names("a"), ","); names != "b,d" {
+		t.Errorf("got names %q, expected: %q", names, "b,d")
+	}
+	if rel, v, err := x.Lookup("stat", "r.zip", "r.zip/a/d"); err != nil || rel != "a/d" || v != "d" {
+		t.Errorf("got %q %v %v, expected: %q %q", rel, v, err, "a/d", "d")
+	}
+	if _, _, err := x.Lookup("stat", "r.zip", "r.zip/x"); err == nil || !strings.HasPrefix(err.Error(), "stat r.zip/x") {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestEval(t *testing.T) {
+	links := map[string]string{
+		"a/up":   "..",
+		"a/abs":  "/b",
+		"a/self": "self",
+		"b/c":    "../a",
+		"/x":     "y",
+	}
+	readlink := func(p string) (string, bool, error) {
+		target, ok := links[p]
+		return target, ok, nil
+	}
+	tests := []struct {
+		p        string
+		chroot   bool
+		expected string
+	}{
+		{"a/up/b", true, "b"},
+		{"a/abs/c/f", true, "a/f"},
+		{"../../a/f", true, "a/f"},
+		{"/b/c", true, "a"},
+		{"a/missing/../f", true, "a/f"},
+		{"a/abs", false, "/b"},
+		{"../a/f", false, "../a/f"},
+		{"/x/f", false, "/y/f"},
+		{"a/self", true, ""},
+	}
+	for _, test := range tests {
+		r := Resolver{Readlink: readlink, Chroot: test.chroot}
+		resolved, err := r.Eval(test.p)
+		if test.expected == "" {
+			if err != ErrTooManyLinks {
+				t.Errorf("%q: expected too many links, got %q %v", test.p, resolved, err)
+			}
+			continue
+		}
+		if err != nil || resolved != test.expected {
+			t.Errorf("%q: got %q %v, expected: %q", test.p, resolved, err, test.expected)
+		}
+	}
+}
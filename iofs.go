@@ -0,0 +1,165 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FromFS returns a Fs that reads from fsys, e.g: embed.FS, fstest.MapFS or
+// os.DirFS. Paths are resolved from the root of fsys, so "." "/" and "./a"
+// are the same as "." "." and "a". The returned Fs is also an Opener.
+//
+// Note that iofs.FS follows symbolic links, so they are listed as their
+// targets.
+func FromFS(fsys iofs.FS) Fs {
+	return &ioFs{fsys}
+}
+
+type ioFs struct {
+	fsys iofs.FS
+}
+
+// name converts the given path to a valid iofs.FS path.
+func (f *ioFs) name(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (f *ioFs) Stat(path string) (os.FileInfo, error) {
+	return iofs.Stat(f.fsys, f.name(path))
+}
+
+func (f *ioFs) ReadDir(path string) ([]string, error) {
+	entries, err := iofs.ReadDir(f.fsys, f.name(path))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, nil
+}
+
+func (f *ioFs) Open(path string) (io.ReadCloser, error) {
+	return f.fsys.Open(f.name(path))
+}
+
+// ToFS returns a iofs.FS view of the given Fs, rooted at dir. Files can be
+// read only if the Fs is an Opener.
+func ToFS(f Fs, dir string) iofs.FS {
+	return &treeFS{f, dir}
+}
+
+type treeFS struct {
+	fs  Fs
+	dir string
+}
+
+func (t *treeFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrInvalid}
+	}
+	p := filepath.Join(t.dir, filepath.FromSlash(name))
+	fi, err := t.fs.Stat(p)
+	if err == nil && fi == nil {
+		err = iofs.ErrNotExist
+	}
+	if err != nil {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &treeFile{t: t, name: name, path: p, fi: fi}, nil
+}
+
+// treeFile is an open file of a treeFS. Its content is opened on the first
+// read, and its entries on the first ReadDir.
+type treeFile struct {
+	t     *treeFS
+	name  string
+	path  string
+	fi    os.FileInfo
+	rc    io.ReadCloser
+	names []string
+	read  bool
+}
+
+func (f *treeFile) Stat() (iofs.FileInfo, error) { return f.fi, nil }
+
+func (f *treeFile) Read(b []byte) (int, error) {
+	if f.fi.IsDir() {
+		return 0, &iofs.PathError{Op: "read", Path: f.name, Err: errors.New("is a directory")}
+	}
+	if f.rc == nil {
+		o, ok := f.t.fs.(Opener)
+		if !ok {
+			return 0, &iofs.PathError{Op: "read", Path: f.name, Err: errors.New("file system is not an Opener")}
+		}
+		rc, err := o.Open(f.path)
+		if err != nil {
+			return 0, &iofs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		f.rc = rc
+	}
+	return f.rc.Read(b)
+}
+
+func (f *treeFile) Close() error {
+	if f.rc != nil {
+		return f.rc.Close()
+	}
+	return nil
+}
+
+func (f *treeFile) ReadDir(n int) ([]iofs.DirEntry, error) {
+	if !f.fi.IsDir() {
+		return nil, &iofs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	if !f.read {
+		names, err := f.t.fs.ReadDir(f.path)
+		if err != nil {
+			return nil, &iofs.PathError{Op: "readdir", Path: f.name, Err: err}
+		}
+		sort.Strings(names)
+		f.names, f.read = names, true
+	}
+	count := len(f.names)
+	if n > 0 && n < count {
+		count = n
+	}
+	if n > 0 && count == 0 {
+		return nil, io.EOF
+	}
+	entries := make([]iofs.DirEntry, 0, count)
+	for _, name := range f.names[:count] {
+		fi, err := f.t.fs.Stat(filepath.Join(f.path, name))
+		if err == nil && fi == nil {
+			err = iofs.ErrNotExist
+		}
+		if err != nil {
+			f.names = f.names[len(entries):]
+			return entries, &iofs.PathError{Op: "readdir", Path: path.Join(f.name, name), Err: err}
+		}
+		entries = append(entries, dirEntry{fi})
+	}
+	f.names = f.names[count:]
+	return entries, nil
+}
+
+// dirEntry is a iofs.DirEntry of a FileInfo.
+type dirEntry struct {
+	fi os.FileInfo
+}
+
+func (e dirEntry) Name() string                 { return e.fi.Name() }
+func (e dirEntry) IsDir() bool                  { return e.fi.IsDir() }
+func (e dirEntry) Type() iofs.FileMode          { return e.fi.Mode().Type() }
+func (e dirEntry) Info() (iofs.FileInfo, error) { return e.fi, nil }
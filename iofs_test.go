@@ -0,0 +1,97 @@
+package tree
+
+import (
+	"errors"
+	iofs "io/fs"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+)
+
+var ioFsMap = fstest.MapFS{
+	"a":         {Data: []byte("a")},
+	"b/c":       {Data: []byte("bc")},
+	"b/d/e":     {Data: []byte("bde")},
+	"b/d/f.txt": {Data: []byte("bdf"), Mode: 0755},
+	"g":         {Mode: iofs.ModeDir},
+}
+
+var ioFsTests = []treeTest{
+	{"basic", &Options{}, `.
+├── a
+├── b
+│   ├── c
+│   └── d
+│       ├── e
+│       └── f.txt
+└── g
+`, 3, 4},
+	{"byte-size", &Options{ByteSize: true}, `[          9]  .
+├── [          1]  a
+├── [          8]  b
+│   ├── [          2]  c
+│   └── [          6]  d
+│       ├── [          3]  e
+│       └── [          3]  f.txt
+└── [          0]  g
+`, 3, 4},
+	{"pattern", &Options{Pattern: "*.txt", Prune: true}, `.
+└── b
+    └── d
+        └── f.txt
+`, 2, 1},
+}
+
+func TestFromFS(t *testing.T) {
+	for _, test := range ioFsTests {
+		test.opts.Fs = FromFS(ioFsMap)
+		test.opts.OutFile = out
+		inf := New(".")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestFromFSOpen(t *testing.T) {
+	rc, err := FromFS(ioFsMap).(Opener).Open("./b/d/../c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "bc" {
+		t.Errorf("got: %q, expected: %q", b, "bc")
+	}
+}
+
+func TestToFS(t *testing.T) {
+	fsys := ToFS(FromFS(ioFsMap), ".")
+	if err := fstest.TestFS(fsys, "a", "b/c", "b/d/e", "b/d/f.txt", "g"); err != nil {
+		t.Error(err)
+	}
+	sub := ToFS(FromFS(ioFsMap), "b")
+	if err := fstest.TestFS(sub, "c", "d/e", "d/f.txt"); err != nil {
+		t.Error(err)
+	}
+	// Content can't be read from a Fs that is not an Opener.
+	fs.clean().addFile("root", &file{name: "root", files: []*file{{name: "a", size: 1}}})
+	f, err := ToFS(fs, "root").Open("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 1)); err == nil {
+		t.Error("expected read error")
+	}
+	if _, err := ToFS(fs, "root").Open("../a"); !errors.Is(err, iofs.ErrInvalid) {
+		t.Error("expected invalid path error")
+	}
+}
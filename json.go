@@ -0,0 +1,100 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// jsonPrinter prints the tree as JSON, using the same layout as `tree -J`:
+//
+//	[
+//	  {"type":"directory","name":"root","contents":[
+//	    {"type":"file","name":"a"}
+//	  ]}
+//	,
+//	  {"type":"report","directories":0,"files":1}
+//	]
+type jsonPrinter struct{}
+
+func (jsonPrinter) begin(opts *Options) { fmt.Fprintln(opts.OutFile, "[") }
+
+func (jsonPrinter) print(node *Node, i int, opts *Options) {
+	if i > 0 {
+		fmt.Fprintln(opts.OutFile, ",")
+	}
+	node.printJSON("  ", opts)
+	fmt.Fprintln(opts.OutFile)
+}
+
+func (jsonPrinter) end(dirs, files int, opts *Options) {
+	if !opts.NoReport {
+		report := fmt.Sprintf(`{"type":"report","directories":%d`, dirs)
+		if !opts.DirsOnly {
+			report += fmt.Sprintf(`,"files":%d`, files)
+		}
+		fmt.Fprintf(opts.OutFile, ",\n  %s}\n", report)
+	}
+	fmt.Fprintln(opts.OutFile, "]")
+}
+
+func (node *Node) printJSON(indent string, opts *Options) {
+	w := opts.OutFile
+	fmt.Fprintf(w, `%s{"type":"%s","name":%s`, indent, node.kind(), jsonString(node.name(opts)))
+	if node.FileInfo != nil {
+		for _, f := range node.jsonFields(opts) {
+			fmt.Fprintf(w, ",%s", f)
+		}
+	}
+	if node.err != nil {
+		fmt.Fprintf(w, `,"error":%s}`, jsonString(node.errMsg()))
+		return
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		target, _, recursive := node.link(opts)
+		fmt.Fprintf(w, `,"target":%s`, jsonString(target))
+		if recursive {
+			fmt.Fprint(w, `,"error":"recursive, not followed"`)
+		}
+	}
+	if !node.IsDir() && len(node.nodes) == 0 {
+		fmt.Fprint(w, "}")
+		return
+	}
+	fmt.Fprint(w, `,"contents":[`)
+	for i, nnode := range node.nodes {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintln(w)
+		nnode.printJSON(indent+"  ", opts)
+	}
+	if len(node.nodes) > 0 {
+		fmt.Fprintf(w, "\n%s", indent)
+	}
+	fmt.Fprint(w, "]}")
+}
+
+// jsonFields returns the metadata fields enabled by the options, encoded as
+// `"key":value` pairs.
+func (node *Node) jsonFields(opts *Options) (fields []string) {
+	for _, f := range node.fields(opts) {
+		if s, ok := f.value.(string); ok {
+			fields = append(fields, fmt.Sprintf(`"%s":%s`, f.key, jsonString(s)))
+		} else {
+			fields = append(fields, fmt.Sprintf(`"%s":%d`, f.key, f.value))
+		}
+	}
+	return
+}
+
+// jsonString returns s as a JSON string literal.
+func jsonString(s string) string {
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"encoding/json"
+	"os"
+	"syscall"
+	"testing"
+)
+
+var jsonTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, JSON: true}, `[
+  {"type":"directory","name":"root","contents":[
+    {"type":"file","name":"a"},
+    {"type":"directory","name":"c","contents":[
+      {"type":"file","name":"d \"quoted\""}
+    ]},
+    {"type":"directory","name":"e","contents":[]},
+    {"type":"file","name":"bad","error":"stat failed"}
+  ]}
+,
+  {"type":"report","directories":2,"files":2}
+]
+`, 2, 2},
+	{"dirs + noreport", &Options{Fs: fs, OutFile: out, JSON: true, DirsOnly: true, NoReport: true}, `[
+  {"type":"directory","name":"root","contents":[
+    {"type":"directory","name":"c","contents":[]},
+    {"type":"directory","name":"e","contents":[]},
+    {"type":"file","name":"bad","error":"stat failed"}
+  ]}
+]
+`, 2, 0},
+	{"metadata", &Options{Fs: fs, OutFile: out, JSON: true, FileMode: true, ByteSize: true, Inodes: true, Device: true, DeepLevel: 1}, `[
+  {"type":"directory","name":"root","inode":1,"dev":9,"mode":"0755","prot":"drwxr-xr-x","size":100,"contents":[
+    {"type":"file","name":"a","inode":2,"dev":9,"mode":"0644","prot":"-rw-r--r--","size":100},
+    {"type":"directory","name":"c","inode":3,"dev":9,"mode":"0700","prot":"drwx------","contents":[]},
+    {"type":"directory","name":"e","inode":4,"dev":9,"mode":"0755","prot":"drwxr-xr-x","contents":[]},
+    {"type":"file","name":"bad","error":"stat failed"}
+  ]}
+,
+  {"type":"report","directories":2,"files":1}
+]
+`, 2, 1},
+}
+
+func TestJSON(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir | 0755,
+		stat: &syscall.Stat_t{Ino: 1, Dev: 9},
+		files: []*file{
+			{name: "a", size: 100, mode: 0644, stat: &syscall.Stat_t{Ino: 2, Dev: 9}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:  "c",
+				mode:  os.ModeDir | 0700,
+				stat:  &syscall.Stat_t{Ino: 3, Dev: 9},
+				files: []*file{{name: `d "quoted"`, size: 50}},
+			},
+			{name: "e", mode: os.ModeDir | 0755, stat: &syscall.Stat_t{Ino: 4, Dev: 9}, files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range jsonTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		var v []interface{}
+		if err := json.Unmarshal([]byte(out.str), &v); err != nil {
+			t.Errorf("%s: invalid JSON output: %v", test.name, err)
+		}
+		out.clear()
+	}
+}
+
+func TestJSONModes(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir | 0755,
+		files: []*file{
+			{name: "run", mode: os.ModeSetuid | 0755},
+			{name: "tmp", mode: os.ModeDir | os.ModeSticky | 0777, files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, JSON: true, FileMode: true, NoReport: true}
+	inf := New(root.name)
+	inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	p.End(0, 0)
+	expected := `[
+  {"type":"directory","name":"root","mode":"0755","prot":"drwxr-xr-x","contents":[
+    {"type":"file","name":"run","mode":"0755","prot":"-rwsr-xr-x"},
+    {"type":"directory","name":"tmp","mode":"0777","prot":"drwxrwxrwt","contents":[]}
+  ]}
+]
+`
+	if !out.equal(expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+}
+
+func TestProtMode(t *testing.T) {
+	tests := []struct {
+		mode     os.FileMode
+		expected string
+	}{
+		{0644, "-rw-r--r--"},
+		{os.ModeDir | 0755, "drwxr-xr-x"},
+		{os.ModeSymlink | 0777, "lrwxrwxrwx"},
+		{os.ModeNamedPipe | 0600, "prw-------"},
+		{os.ModeSocket | 0755, "srwxr-xr-x"},
+		{os.ModeDevice | 0660, "brw-rw----"},
+		{os.ModeDevice | os.ModeCharDevice | 0666, "crw-rw-rw-"},
+		{os.ModeSetuid | os.ModeSetgid | 0755, "-rwsr-sr-x"},
+		{os.ModeSetuid | 0644, "-rwSr--r--"},
+		{os.ModeDir | os.ModeSticky | 0777, "drwxrwxrwt"},
+		{os.ModeDir | os.ModeSticky | 0770, "drwxrwx--T"},
+	}
+	for _, test := range tests {
+		node := &Node{FileInfo: &file{mode: test.mode}}
+		if prot := node.protMode(); prot != test.expected {
+			t.Errorf("%v: got %q, expected: %q", test.mode, prot, test.expected)
+		}
+	}
+}
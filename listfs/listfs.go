@@ -0,0 +1,177 @@
+// Package listfs implements tree.Fs over a listing of paths, e.g: the output
+// of find, git ls-files or tar -t, or over a tab-indented outline.
+package listfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// FS is a tree.Fs of a listing of paths. Parent directories that are not
+// listed are implied, and paths that end with a slash are directories.
+// Files have no size, mode or time besides their type.
+type FS struct {
+	// Root is the path of the listing root, e.g: "files.txt".
+	Root  string
+	files map[string]*file
+}
+
+// file is a listed path, and the names of its entries.
+type file struct {
+	dir   bool
+	names []string
+}
+
+// New returns an empty FS, rooted at ".".
+func New() *FS {
+	return &FS{Root: ".", files: map[string]*file{".": {dir: true}}}
+}
+
+// Read returns the FS of the paths read from r. They are separated by
+// newlines, or by NUL characters if there is one in the beginning of the
+// listing, e.g: the output of find -print0.
+func Read(r io.Reader) (*FS, error) {
+	fs := New()
+	br := bufio.NewReader(r)
+	sep := byte('\n')
+	if b, _ := br.Peek(br.Size()); bytes.IndexByte(b, 0) != -1 {
+		sep = 0
+	}
+	for {
+		line, err := br.ReadString(sep)
+		if line = strings.TrimRight(line, "\x00\r\n"); line != "" {
+			fs.Add(line)
+		}
+		if err == io.EOF {
+			return fs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadTab returns the FS of the tab-indented outline read from r. Every
+// line is an entry under the last entry that is indented with one tab less,
+// and entries that end with a slash are directories:
+//
+//	cmd/
+//		tree/
+//			tree.go
+//	README.md
+func ReadTab(r io.Reader) (*FS, error) {
+	fs := New()
+	// The names of the parents of the current line.
+	var parents []string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		name := strings.TrimLeft(line, "\t")
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		depth := len(line) - len(name)
+		if depth > len(parents) {
+			return nil, fmt.Errorf("line %d: %s is indented more than one level under its parent", n, name)
+		}
+		parents = append(parents[:depth], strings.TrimSuffix(name, "/"))
+		if strings.HasSuffix(name, "/") {
+			fs.Add(strings.Join(parents, "/") + "/")
+		} else {
+			fs.Add(strings.Join(parents, "/"))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Add adds the path to the listing. It's a directory if it ends with a
+// slash, or if it has entries. Leading slashes are ignored.
+func (fs *FS) Add(name string) {
+	dir := strings.HasSuffix(name, "/")
+	name = vpath.Clean(name)
+	if name == "." {
+		return
+	}
+	if f := fs.add(name); dir {
+		f.dir = true
+	}
+}
+
+// add adds the path and its parents, and returns its file.
+func (fs *FS) add(name string) *file {
+	if f, ok := fs.files[name]; ok {
+		return f
+	}
+	parent := fs.files["."]
+	if dir := path.Dir(name); dir != "." {
+		parent = fs.add(dir)
+		parent.dir = true
+	}
+	f := new(file)
+	fs.files[name] = f
+	parent.names = append(parent.names, path.Base(name))
+	return f
+}
+
+// lookup returns the file with the given name.
+func (fs *FS) lookup(op, name string) (*file, error) {
+	rel, err := vpath.Rel(fs.Root, name)
+	if err == nil {
+		if f, ok := fs.files[rel]; ok {
+			return f, nil
+		}
+	}
+	return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// Stat returns the FileInfo of the named file.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	f, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if f == fs.files["."] {
+		name = fs.Root
+	}
+	return &fileInfo{filepath.Base(name), f.dir}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	f, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), f.names...), nil
+}
+
+// fileInfo is the FileInfo of a listed path.
+type fileInfo struct {
+	name string
+	dir  bool
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return 0 }
+func (fi *fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,122 @@
+package listfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/a8m/tree"
+)
+
+var tests = []struct {
+	name     string
+	listing  string
+	opts     *tree.Options
+	expected string
+}{
+	{"find", `.
+./a
+./b
+./b/c
+./b/d/e
+./b/d/f.go
+`, &tree.Options{}, `files.txt
+├── a
+└── b
+    ├── c
+    └── d
+        ├── e
+        └── f.go
+
+2 directories, 4 files
+`},
+	{"print0", "x/y/z\x00x/y/w/\x00/x/a\x00", &tree.Options{DirsOnly: true}, `files.txt
+└── x
+    └── y
+        └── w
+
+3 directories
+`},
+	{"crlf", "b\r\na/\r\n\r\nc/d\r\n", &tree.Options{Pattern: "d", Prune: true}, `files.txt
+└── c
+    └── d
+
+1 directories, 1 files
+`},
+	{"hidden", ".git/HEAD\nsrc/.env\nsrc/main.go\n", &tree.Options{}, `files.txt
+└── src
+    └── main.go
+
+1 directories, 1 files
+`},
+}
+
+func TestRead(t *testing.T) {
+	for _, test := range tests {
+		fs, err := Read(strings.NewReader(test.listing))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs.Root = "files.txt"
+		out := new(bytes.Buffer)
+		test.opts.Fs = fs
+		test.opts.OutFile = out
+		inf := tree.New(fs.Root)
+		d, f := inf.Visit(test.opts)
+		p := tree.NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if actual := out.String(); actual != test.expected {
+			t.Errorf("%s:\nactual\n%s\n != expect\n%s\n", test.name, actual, test.expected)
+		}
+	}
+}
+
+var tabTests = []struct {
+	name     string
+	outline  string
+	opts     *tree.Options
+	expected string
+}{
+	{"basic", "cmd/\n\ttree/\n\t\ttree.go\n\ndocs/\nREADME.md\r\n", &tree.Options{}, `plan.txt
+├── README.md
+├── cmd
+│   └── tree
+│       └── tree.go
+└── docs
+
+3 directories, 2 files
+`},
+	{"implied", "a\n\tb\n\t\tc/\n\td\ne/f\n", &tree.Options{DirsOnly: true}, `plan.txt
+├── a
+│   └── b
+│       └── c
+└── e
+
+4 directories
+`},
+}
+
+func TestReadTab(t *testing.T) {
+	for _, test := range tabTests {
+		fs, err := ReadTab(strings.NewReader(test.outline))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs.Root = "plan.txt"
+		out := new(bytes.Buffer)
+		test.opts.Fs = fs
+		test.opts.OutFile = out
+		inf := tree.New(fs.Root)
+		d, f := inf.Visit(test.opts)
+		p := tree.NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if actual := out.String(); actual != test.expected {
+			t.Errorf("%s:\nactual\n%s\n != expect\n%s\n", test.name, actual, test.expected)
+		}
+	}
+	if _, err := ReadTab(strings.NewReader("a/\n\t\tb\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an indentation error on line 2, got %v", err)
+	}
+}
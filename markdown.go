@@ -0,0 +1,89 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// markdownPrinter prints the tree as nested Markdown lists. If BaseHREF is
+// set, every entry links to BaseHREF followed by its path relative to the
+// root. With Fenced, the text tree is printed in a code block instead.
+type markdownPrinter struct{}
+
+func (markdownPrinter) begin(opts *Options) {
+	if opts.Fenced {
+		fmt.Fprintln(opts.OutFile, "```text")
+	}
+}
+
+func (markdownPrinter) print(node *Node, i int, opts *Options) {
+	if opts.Fenced {
+		node.print("", opts)
+		return
+	}
+	if i > 0 {
+		fmt.Fprintln(opts.OutFile)
+	}
+	node.printMarkdown("", "", opts)
+}
+
+func (markdownPrinter) end(dirs, files int, opts *Options) {
+	w := opts.OutFile
+	if !opts.NoReport {
+		fmt.Fprintf(w, "\n%s\n", report(dirs, files, opts))
+	}
+	if opts.Fenced {
+		fmt.Fprintln(w, "```")
+	}
+}
+
+// printMarkdown prints the node as a list item. rel is the node path
+// relative to the root, used to build its link.
+func (node *Node) printMarkdown(indent, rel string, opts *Options) {
+	w := opts.OutFile
+	name := markdownString(node.name(opts))
+	if node.err != nil {
+		fmt.Fprintf(w, "%s- %s \\[%s\\]\n", indent, name, markdownString(node.errMsg()))
+		return
+	}
+	if node.IsDir() {
+		name += "/"
+	}
+	if opts.Quotes {
+		name = fmt.Sprintf("\"%s\"", name)
+	}
+	if opts.BaseHREF != "" {
+		name = fmt.Sprintf("[%s](<%s>)", name, node.href(rel, opts))
+	}
+	if node.IsDir() {
+		name = "**" + name + "**"
+	}
+	if props := node.props(opts); len(props) > 0 {
+		name = fmt.Sprintf("`[%s]` %s", strings.Join(strings.Fields(strings.Join(props, " ")), " "), name)
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		target, _, recursive := node.link(opts)
+		name += " -> " + markdownString(target)
+		if recursive {
+			name += " \\[recursive, not followed\\]"
+		}
+	}
+	fmt.Fprintf(w, "%s- %s\n", indent, name)
+	for _, nnode := range node.nodes {
+		nnode.printMarkdown(indent+"  ", path.Join(rel, filepath.Base(nnode.path)), opts)
+	}
+}
+
+// markdownString escapes the characters that have a meaning in Markdown
+// inline text. Newlines are replaced with spaces to keep the list valid.
+func markdownString(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+		`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`, `!`, `\!`,
+		"\n", " ",
+	)
+	return r.Replace(s)
+}
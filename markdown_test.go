@@ -0,0 +1,50 @@
+package tree
+
+import (
+	"os"
+	"testing"
+)
+
+var markdownTests = []treeTest{
+	{"list", &Options{Fs: fs, OutFile: out, Markdown: true}, "- **root/**\n" +
+		"  - a\\_b.go\n" +
+		"  - **c/**\n" +
+		"    - \\[d\\]\n" +
+		"  - bad \\[stat failed\\]\n" +
+		"\n1 directories, 2 files\n", 1, 2},
+	{"links + size", &Options{Fs: fs, OutFile: out, Markdown: true, BaseHREF: "docs", ByteSize: true, NoReport: true}, "- `[150]` **[root/](<docs/>)**\n" +
+		"  - `[100]` [a\\_b.go](<docs/a_b.go>)\n" +
+		"  - `[50]` **[c/](<docs/c/>)**\n" +
+		"    - `[50]` [\\[d\\]](<docs/c/%5Bd%5D>)\n" +
+		"  - bad \\[stat failed\\]\n", 1, 2},
+	{"fenced", &Options{Fs: fs, OutFile: out, Markdown: true, Fenced: true, DirsOnly: true}, "```text\n" + `root
+├── c
+└── bad [stat failed]
+
+1 directories
+` + "```\n", 1, 0},
+}
+
+func TestMarkdown(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir,
+		files: []*file{
+			{name: "a_b.go", size: 100},
+			{name: "bad"}, // stat fails on this file
+			{name: "c", mode: os.ModeDir, files: []*file{{name: "[d]", size: 50}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range markdownTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
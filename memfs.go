@@ -0,0 +1,322 @@
+package tree
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// MemFs is an in-memory Fs, that can be used to build fixtures without
+// touching the disk. It's safe for concurrent use, and it's also an Opener
+// and a Readlinker. Parent directories are created as needed, and errors
+// can be injected per path with SetError.
+//
+//	fs := tree.NewMemFs()
+//	fs.WriteFile("root/a", []byte("a"), 0644)
+//	fs.Symlink("a", "root/b")
+//	fs.SetError("readdir", "root/c", errors.New("permission denied"))
+type MemFs struct {
+	// Clock returns the time of the changes, that is used for the new
+	// files and for their change time. It defaults to time.Now.
+	Clock func() time.Time
+	mu    sync.RWMutex
+	files map[string]*memFile
+	errs  map[string]error
+}
+
+// MemStat is the status of a MemFs file, that is returned by the Sys method
+// of its FileInfo. The owner names are the ids.
+type MemStat struct {
+	Uid   int
+	Gid   int
+	Atime time.Time
+	Ctime time.Time
+}
+
+// Owner implements the SysInfo interface.
+func (st *MemStat) Owner() (uid, gid uint64, user, group string) {
+	uid, gid = uint64(st.Uid), uint64(st.Gid)
+	return uid, gid, strconv.FormatUint(uid, 10), strconv.FormatUint(gid, 10)
+}
+
+// Times implements the SysInfo interface.
+func (st *MemStat) Times() (atime, ctime time.Time) { return st.Atime, st.Ctime }
+
+// memFile is a MemFs file, and the names of its entries if it's a directory.
+type memFile struct {
+	mode    os.FileMode
+	data    []byte
+	target  string
+	modTime time.Time
+	stat    MemStat
+	names   []string
+}
+
+// NewMemFs returns an empty MemFs.
+func NewMemFs() *MemFs {
+	return &MemFs{files: make(map[string]*memFile), errs: make(map[string]error)}
+}
+
+func (fs *MemFs) now() time.Time {
+	if fs.Clock != nil {
+		return fs.Clock()
+	}
+	return time.Now()
+}
+
+// Mkdir creates the named directory, and its parents if needed.
+func (fs *MemFs) Mkdir(name string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, err := fs.create("mkdir", name, os.ModeDir|perm.Perm())
+	return err
+}
+
+// WriteFile writes data to the named file, and creates it if needed.
+func (fs *MemFs) WriteFile(name string, data []byte, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f, err := fs.create("write", name, perm.Perm())
+	if err != nil {
+		return err
+	}
+	f.data = append([]byte(nil), data...)
+	f.modTime = fs.now()
+	f.stat.Ctime = f.modTime
+	return nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (fs *MemFs) Symlink(oldname, newname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name := filepath.Clean(newname)
+	if _, ok := fs.files[name]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	f, err := fs.create("symlink", name, os.ModeSymlink|0777)
+	if err != nil {
+		return err
+	}
+	f.target = oldname
+	return nil
+}
+
+// create returns the named file, and creates it and its parents with the
+// given mode if needed. Existing files must be of the same type.
+func (fs *MemFs) create(op, name string, mode os.FileMode) (*memFile, error) {
+	name = filepath.Clean(name)
+	if f, ok := fs.files[name]; ok {
+		if f.mode.Type() != mode.Type() {
+			return nil, &os.PathError{Op: op, Path: name, Err: errors.New("file exists with another type")}
+		}
+		return f, nil
+	}
+	if dir := filepath.Dir(name); dir != name {
+		parent, err := fs.create(op, dir, os.ModeDir|0755)
+		if err != nil {
+			return nil, err
+		}
+		parent.names = append(parent.names, filepath.Base(name))
+	}
+	now := fs.now()
+	f := &memFile{mode: mode, modTime: now, stat: MemStat{Atime: now, Ctime: now}}
+	fs.files[name] = f
+	return f, nil
+}
+
+// change calls fn with the named file, and updates its change time.
+func (fs *MemFs) change(op, name string, fn func(*memFile)) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f, ok := fs.files[filepath.Clean(name)]
+	if !ok {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	fn(f)
+	f.stat.Ctime = fs.now()
+	return nil
+}
+
+// Chmod sets the permission and the special bits of the named file.
+func (fs *MemFs) Chmod(name string, mode os.FileMode) error {
+	return fs.change("chmod", name, func(f *memFile) {
+		f.mode = f.mode.Type() | mode&^os.ModeType
+	})
+}
+
+// Chown sets the owner ids of the named file.
+func (fs *MemFs) Chown(name string, uid, gid int) error {
+	return fs.change("chown", name, func(f *memFile) {
+		f.stat.Uid, f.stat.Gid = uid, gid
+	})
+}
+
+// Chtimes sets the access and modification times of the named file.
+func (fs *MemFs) Chtimes(name string, atime, mtime time.Time) error {
+	return fs.change("chtimes", name, func(f *memFile) {
+		f.stat.Atime, f.modTime = atime, mtime
+	})
+}
+
+// RemoveAll removes the named file, and its entries if it's a directory.
+func (fs *MemFs) RemoveAll(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := fs.files[name]; !ok {
+		return nil
+	}
+	prefix := name + string(filepath.Separator)
+	for path := range fs.files {
+		if path == name || strings.HasPrefix(path, prefix) {
+			delete(fs.files, path)
+		}
+	}
+	if parent, ok := fs.files[filepath.Dir(name)]; ok {
+		base := filepath.Base(name)
+		for i, n := range parent.names {
+			if n == base {
+				parent.names = append(parent.names[:i:i], parent.names[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// SetError makes the operation op fail with err for the named file, until
+// it's called with a nil err. The operations are "stat", "readdir", "open"
+// and "readlink".
+func (fs *MemFs) SetError(op, name string, err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	key := op + ":" + filepath.Clean(name)
+	if err == nil {
+		delete(fs.errs, key)
+	} else {
+		fs.errs[key] = err
+	}
+}
+
+// lookup returns the named file, or the error of the operation.
+func (fs *MemFs) lookup(op, name string) (*memFile, error) {
+	name = filepath.Clean(name)
+	if err, ok := fs.errs[op+":"+name]; ok {
+		return nil, err
+	}
+	if f, ok := fs.files[name]; ok {
+		return f, nil
+	}
+	return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+// Stat returns the FileInfo of the named file, without following links.
+func (fs *MemFs) Stat(name string) (os.FileInfo, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	f, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	stat := f.stat
+	return &memFileInfo{
+		name:    filepath.Base(name),
+		size:    int64(len(f.data)),
+		mode:    f.mode,
+		modTime: f.modTime,
+		stat:    &stat,
+	}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory, in the
+// order they were created.
+func (fs *MemFs) ReadDir(name string) ([]string, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	f, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !f.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return append([]string(nil), f.names...), nil
+}
+
+// Open opens the content of the named file.
+func (fs *MemFs) Open(name string) (io.ReadCloser, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	f, err := fs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if f.mode.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// Readlink returns the target of the named symbolic link, and the path it
+// resolves to.
+func (fs *MemFs) Readlink(name string) (target, resolved string, err error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	f, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", "", err
+	}
+	if f.mode&os.ModeSymlink == 0 {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	p := f.target
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(name), p)
+	}
+	if resolved, err = fs.eval(p); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return f.target, resolved, nil
+}
+
+// eval returns the path after the evaluation of its symbolic links, like
+// filepath.EvalSymlinks. Missing files are resolved as is.
+func (fs *MemFs) eval(path string) (string, error) {
+	vol := filepath.VolumeName(path)
+	r := vpath.Resolver{Readlink: func(p string) (string, bool, error) {
+		f, ok := fs.files[vol+filepath.FromSlash(p)]
+		if !ok || f.mode&os.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		target := f.target[len(filepath.VolumeName(f.target)):]
+		return filepath.ToSlash(target), true, nil
+	}}
+	resolved, err := r.Eval(filepath.ToSlash(path[len(vol):]))
+	return vol + filepath.FromSlash(resolved), err
+}
+
+// memFileInfo is the FileInfo of a MemFs file, at the time it was stat'ed.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	stat    *MemStat
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() interface{}   { return fi.stat }
@@ -0,0 +1,209 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+var memTime = time.Date(2020, time.March, 4, 5, 6, 0, 0, time.UTC)
+
+// newMemFs returns the MemFs of the tests:
+//
+//	root
+//	├── a
+//	├── b -> a
+//	├── c
+//	│   └── d
+//	├── e -> missing
+//	└── f
+func newMemFs(t *testing.T) *MemFs {
+	fs := NewMemFs()
+	fs.Clock = func() time.Time { return memTime }
+	for _, err := range []error{
+		fs.WriteFile("root/a", []byte("aaa"), 0644),
+		fs.Symlink("a", "root/b"),
+		fs.WriteFile("root/c/d", []byte("dddd"), 0600),
+		fs.Symlink("missing", "root/e"),
+		fs.Mkdir("root/f", 0700),
+		fs.Chown("root/c/d", 1000, 100),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+var memFsTests = []treeTest{
+	{"basic", &Options{}, `root
+├── a
+├── b -> a
+├── c
+│   └── d
+├── e -> missing
+└── f
+`, 2, 4},
+	{"props", &Options{FileMode: true, ShowUid: true, ByteSize: true, LastMod: true, Now: memTime}, `[          7]  root
+├── [-rw-r--r-- 0                  3 Mar 04 05:06]  a
+├── [Lrwxrwxrwx 0                  0 Mar 04 05:06]  b -> a
+├── [          4]  c
+│   └── [-rw------- 1000               4 Mar 04 05:06]  d
+├── [Lrwxrwxrwx 0                  0 Mar 04 05:06]  e -> missing
+└── [          0]  f
+`, 2, 4},
+	{"follow", &Options{FollowLink: true}, `root
+├── a
+├── b -> a
+├── c
+│   └── d
+├── e -> missing
+└── f
+`, 2, 4},
+}
+
+func TestMemFs(t *testing.T) {
+	for _, test := range memFsTests {
+		test.opts.Fs = newMemFs(t)
+		test.opts.OutFile = out
+		inf := New("root")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestMemFsErrors(t *testing.T) {
+	fs := newMemFs(t)
+	fs.SetError("stat", "root/a", errors.New("stat root/a: input/output error"))
+	fs.SetError("readdir", "root/c", &os.PathError{Op: "open", Path: "root/c", Err: os.ErrPermission})
+	opts := &Options{Fs: fs, OutFile: out}
+	inf := New("root")
+	inf.Visit(opts)
+	inf.Print(opts)
+	expected := `root
+├── b -> a
+├── c [permission denied]
+├── e -> missing
+├── f
+└── a [input/output error]
+`
+	if !out.equal(expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+	fs.SetError("stat", "root/a", nil)
+	if _, err := fs.Stat("root/a"); err != nil {
+		t.Errorf("expected the error to be cleared, got: %v", err)
+	}
+}
+
+func TestMemFsChanges(t *testing.T) {
+	fs := newMemFs(t)
+	atime, mtime := memTime.Add(-time.Hour), memTime.Add(-2*time.Hour)
+	if err := fs.Chmod("root/a", 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chtimes("root/a", atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat("root/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0755|os.ModeSetuid || !fi.ModTime().Equal(mtime) {
+		t.Errorf("got mode %v and time %v", fi.Mode(), fi.ModTime())
+	}
+	if st := fi.Sys().(*MemStat); !st.Atime.Equal(atime) {
+		t.Errorf("got access time %v, expected: %v", st.Atime, atime)
+	}
+	if err := fs.Chown("root/missing", 0, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got: %v", err)
+	}
+	if err := fs.WriteFile("root/c", nil, 0644); err == nil {
+		t.Error("expected error when writing a directory")
+	}
+	if err := fs.Symlink("a", "root/c/d"); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected exist error, got: %v", err)
+	}
+	if err := fs.RemoveAll("root/c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("root/c/d"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected not exist error, got: %v", err)
+	}
+	names, _ := fs.ReadDir("root")
+	if fmt.Sprint(names) != "[a b e f]" {
+		t.Errorf("got entries: %v", names)
+	}
+}
+
+func TestMemFsLinks(t *testing.T) {
+	fs := newMemFs(t)
+	fs.Symlink("../root/c", "root/g")
+	fs.Symlink("g/d", "root/h")
+	for _, test := range []struct {
+		name     string
+		target   string
+		resolved string
+	}{
+		{"root/b", "a", "root/a"},
+		{"root/e", "missing", "root/missing"},
+		{"root/h", "g/d", "root/c/d"},
+	} {
+		target, resolved, err := fs.Readlink(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != test.target || resolved != test.resolved {
+			t.Errorf("%s: got %q, %q, expected: %q, %q", test.name, target, resolved, test.target, test.resolved)
+		}
+	}
+	if _, _, err := fs.Readlink("root/a"); err == nil {
+		t.Error("expected error for a regular file")
+	}
+	rc, err := fs.Open("root/c/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "dddd" {
+		t.Errorf("got: %q, expected: %q", b, "dddd")
+	}
+}
+
+func TestMemFsConcurrency(t *testing.T) {
+	fs := newMemFs(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("root/f/%d", i)
+			fs.WriteFile(name, []byte(name), 0644)
+			fs.Chown(name, i, i)
+		}(i)
+		go func() {
+			defer wg.Done()
+			inf := New("root")
+			inf.Visit(&Options{Fs: fs})
+		}()
+	}
+	wg.Wait()
+	if names, _ := fs.ReadDir("root/f"); len(names) != 8 {
+		t.Errorf("got %d entries, expected: 8", len(names))
+	}
+}
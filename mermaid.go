@@ -0,0 +1,115 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// mermaidPrinter prints the tree as a Mermaid flowchart. Vertices are
+// styled by their class (see: HTMLColor), and if BaseHREF is set, clicking
+// a vertex opens its link. With Fenced, the chart is printed in a "mermaid"
+// code block, ready to be embedded in Markdown.
+type mermaidPrinter struct {
+	// n is the number of vertices, used to generate their ids.
+	n int
+}
+
+// Mermaid styles of the file classes.
+var mermaidStyles = map[string]string{
+	"exec":    "color:green",
+	"archive": "color:red",
+	"media":   "color:magenta",
+	"dir":     "color:blue,font-weight:bold",
+	"fifo":    "color:olive",
+	"socket":  "color:magenta",
+	"device":  "color:goldenrod",
+	"orphan":  "color:red,stroke-dasharray:4",
+	"link":    "color:teal",
+}
+
+func (p *mermaidPrinter) begin(opts *Options) {
+	w := opts.OutFile
+	if opts.Fenced {
+		fmt.Fprintln(w, "```mermaid")
+	}
+	fmt.Fprintln(w, "flowchart LR")
+	for _, class := range []string{"exec", "archive", "media", "dir", "fifo", "socket", "device", "orphan", "link"} {
+		fmt.Fprintf(w, "    classDef %s %s\n", class, mermaidStyles[class])
+	}
+}
+
+func (p *mermaidPrinter) print(node *Node, i int, opts *Options) {
+	p.printNode(node, "", "", opts)
+}
+
+func (p *mermaidPrinter) end(dirs, files int, opts *Options) {
+	w := opts.OutFile
+	if !opts.NoReport {
+		fmt.Fprintf(w, "    %%%% %s\n", report(dirs, files, opts))
+	}
+	if opts.Fenced {
+		fmt.Fprintln(w, "```")
+	}
+}
+
+// printNode prints the node as a vertex, and an edge from its parent
+// vertex, if it has one. rel is the node path relative to the root.
+func (p *mermaidPrinter) printNode(node *Node, parent, rel string, opts *Options) {
+	w := opts.OutFile
+	id := fmt.Sprintf("n%d", p.n)
+	p.n++
+	label := node.name(opts)
+	var class string
+	if node.err != nil {
+		label += " [" + node.errMsg() + "]"
+		class = "orphan"
+	} else {
+		if node.IsDir() {
+			label += "/"
+		}
+		if opts.Quotes {
+			label = fmt.Sprintf("\"%s\"", label)
+		}
+		if props := node.props(opts); len(props) > 0 {
+			label = fmt.Sprintf("[%s] %s", strings.Join(strings.Fields(strings.Join(props, " ")), " "), label)
+		}
+		if node.Mode()&os.ModeSymlink != 0 {
+			target, _, recursive := node.link(opts)
+			label += " -> " + target
+			if recursive {
+				label += " [recursive, not followed]"
+			}
+		}
+		class = fileClass(node)
+	}
+	vertex := fmt.Sprintf("%s[\"%s\"]", id, mermaidString(label))
+	if class != "" {
+		vertex += ":::" + class
+	}
+	if parent != "" {
+		fmt.Fprintf(w, "    %s --> %s\n", parent, vertex)
+	} else {
+		fmt.Fprintf(w, "    %s\n", vertex)
+	}
+	if node.err != nil {
+		return
+	}
+	if opts.BaseHREF != "" {
+		href := strings.Replace(node.href(rel, opts), `"`, "%22", -1)
+		fmt.Fprintf(w, "    click %s href \"%s\"\n", id, href)
+	}
+	for _, nnode := range node.nodes {
+		p.printNode(nnode, id, path.Join(rel, filepath.Base(nnode.path)), opts)
+	}
+}
+
+// mermaidString escapes s to be used in a quoted Mermaid label.
+func mermaidString(s string) string {
+	r := strings.NewReplacer(
+		"#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;", "`", "#96;", "\n", " ",
+	)
+	return r.Replace(s)
+}
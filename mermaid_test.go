@@ -0,0 +1,60 @@
+package tree
+
+import (
+	"os"
+	"testing"
+)
+
+const mermaidHeader = `flowchart LR
+    classDef exec color:green
+    classDef archive color:red
+    classDef media color:magenta
+    classDef dir color:blue,font-weight:bold
+    classDef fifo color:olive
+    classDef socket color:magenta
+    classDef device color:goldenrod
+    classDef orphan color:red,stroke-dasharray:4
+    classDef link color:teal
+`
+
+var mermaidTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, Mermaid: true}, mermaidHeader + `    n0["root/"]:::dir
+    n0 --> n1["a.tar"]:::archive
+    n0 --> n2["c/"]:::dir
+    n2 --> n3["#quot;d#quot; #lt;e#gt;"]
+    n0 --> n4["bad [stat failed]"]:::orphan
+    %% 1 directories, 2 files
+`, 1, 2},
+	{"fenced + links", &Options{Fs: fs, OutFile: out, Mermaid: true, Fenced: true, BaseHREF: "http://host/", NoReport: true, DeepLevel: 1}, "```mermaid\n" + mermaidHeader + `    n0["root/"]:::dir
+    click n0 href "http://host/"
+    n0 --> n1["a.tar"]:::archive
+    click n1 href "http://host/a.tar"
+    n0 --> n2["c/"]:::dir
+    click n2 href "http://host/c/"
+    n0 --> n3["bad [stat failed]"]:::orphan
+` + "```\n", 1, 1},
+}
+
+func TestMermaid(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir,
+		files: []*file{
+			{name: "a.tar", size: 100},
+			{name: "bad"}, // stat fails on this file
+			{name: "c", mode: os.ModeDir, files: []*file{{name: `"d" <e>`, size: 50}}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range mermaidTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
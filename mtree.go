@@ -0,0 +1,218 @@
+package tree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// mtreePrinter prints the tree as an mtree(5) specification, that can be
+// read by `mtree -f` and libarchive. Entries are written with the type,
+// mode, uid, gid, size, time and link keywords, and the sha256digest
+// keyword if Digest is set and the file system is an Opener. The values
+// that are the most common in a directory are set with a "/set" line.
+// The specification has a single root, and therefore only the first root
+// is printed.
+type mtreePrinter struct {
+	// set holds the keywords of the last "/set" line.
+	set map[string]string
+}
+
+// The keywords that are compressed with "/set" lines.
+var mtreeSetKeywords = []string{"uid", "gid", "mode"}
+
+func (p *mtreePrinter) begin(opts *Options) {}
+
+func (p *mtreePrinter) print(node *Node, i int, opts *Options) {
+	if i > 0 {
+		return
+	}
+	t := opts.Now
+	if t.IsZero() {
+		t = time.Now()
+	}
+	fmt.Fprintf(opts.OutFile, "#\t   tree: %s\n#\t   date: %s\n", node.path, t.Format(time.ANSIC))
+	// The root could not be stat'ed, or its patterns are invalid
+	if node.FileInfo == nil {
+		fmt.Fprintln(opts.OutFile)
+		p.printEntry("", ".", node, nil, opts)
+		return
+	}
+	p.set = make(map[string]string)
+	p.printDir(node, ".", opts)
+}
+
+func (p *mtreePrinter) end(dirs, files int, opts *Options) {}
+
+// printDir prints a directory entry at the given path, followed by its
+// files, and its sub directories.
+func (p *mtreePrinter) printDir(node *Node, path string, opts *Options) {
+	w := opts.OutFile
+	var files, dirs Nodes
+	for _, nnode := range node.nodes {
+		if nnode.FileInfo != nil && nnode.IsDir() {
+			dirs = append(dirs, nnode)
+		} else {
+			files = append(files, nnode)
+		}
+	}
+	kws := make([]map[string]string, len(files))
+	for i, nnode := range files {
+		if nnode.FileInfo != nil {
+			kws[i] = nnode.mtreeKeywords(opts)
+		}
+	}
+	fmt.Fprintf(w, "\n# %s\n", path)
+	p.updateSet(kws, opts)
+	name := filepath.Base(path)
+	if node.depth == 0 {
+		name = "."
+	}
+	p.printEntry("", name, node, node.mtreeKeywords(opts), opts)
+	for i, nnode := range files {
+		p.printEntry("    ", mtreeString(filepath.Base(nnode.path)), nnode, kws[i], opts)
+	}
+	for _, nnode := range dirs {
+		p.printDir(nnode, path+"/"+mtreeString(filepath.Base(nnode.path)), opts)
+	}
+	if node.depth != 0 {
+		fmt.Fprintf(w, "# %s\n", path)
+	}
+	fmt.Fprintln(w, "..")
+}
+
+// updateSet writes a "/set" line with the most common keyword values of
+// the files, if they are different from the current ones.
+func (p *mtreePrinter) updateSet(kws []map[string]string, opts *Options) {
+	set := make(map[string]string)
+	for _, key := range mtreeSetKeywords {
+		count := make(map[string]int)
+		for _, kw := range kws {
+			if v, ok := kw[key]; ok && kw["type"] == "file" {
+				count[v]++
+			}
+		}
+		var max int
+		for v, n := range count {
+			if n > max || n == max && v < set[key] {
+				set[key], max = v, n
+			}
+		}
+	}
+	var changed bool
+	for _, key := range mtreeSetKeywords {
+		changed = changed || set[key] != p.set[key]
+	}
+	if !changed || len(set) == 0 {
+		return
+	}
+	line := "/set type=file"
+	for _, key := range mtreeSetKeywords {
+		if v, ok := set[key]; ok {
+			line += fmt.Sprintf(" %s=%s", key, v)
+		}
+	}
+	fmt.Fprintln(opts.OutFile, line)
+	p.set = set
+}
+
+// printEntry prints the node line, omitting the keywords that are set.
+func (p *mtreePrinter) printEntry(indent, name string, node *Node, kw map[string]string, opts *Options) {
+	w := opts.OutFile
+	if node.FileInfo == nil {
+		fmt.Fprintf(w, "%s# %s: %s\n", indent, name, node.errMsg())
+		return
+	}
+	var fields []string
+	if kw["type"] != "file" {
+		fields = append(fields, "type="+kw["type"])
+	}
+	for _, key := range []string{"uid", "gid", "mode", "size", "time", "link", "sha256digest"} {
+		v, ok := kw[key]
+		if !ok {
+			continue
+		}
+		if set, ok := p.set[key]; ok && set == v {
+			continue
+		}
+		fields = append(fields, key+"="+v)
+	}
+	fmt.Fprintf(w, "%s%-15s %s\n", indent, name, strings.Join(fields, " "))
+	if node.err != nil {
+		fmt.Fprintf(w, "%s# %s: %s\n", indent, name, node.errMsg())
+	}
+}
+
+// mtreeKeywords returns the mtree keywords of the node, and their values.
+func (node *Node) mtreeKeywords(opts *Options) map[string]string {
+	kw := map[string]string{
+		"mode": node.octalMode(),
+		"time": fmt.Sprintf("%d.%09d", node.ModTime().Unix(), node.ModTime().Nanosecond()),
+	}
+	if ok, uid, gid, _, _ := getOwner(node); ok {
+		kw["uid"] = fmt.Sprint(uid)
+		kw["gid"] = fmt.Sprint(gid)
+	}
+	mode := node.Mode()
+	switch {
+	case node.IsDir():
+		kw["type"] = "dir"
+	case mode&os.ModeSymlink != 0:
+		kw["type"] = "link"
+		target, _, _ := node.target(opts)
+		kw["link"] = mtreeString(target)
+	case mode&os.ModeNamedPipe != 0:
+		kw["type"] = "fifo"
+	case mode&os.ModeSocket != 0:
+		kw["type"] = "socket"
+	case mode&os.ModeCharDevice != 0:
+		kw["type"] = "char"
+	case mode&os.ModeDevice != 0:
+		kw["type"] = "block"
+	default:
+		kw["type"] = "file"
+		kw["size"] = fmt.Sprint(node.Size())
+		if digest, ok := node.sha256(opts); ok {
+			kw["sha256digest"] = digest
+		}
+	}
+	return kw
+}
+
+// sha256 returns the hex-encoded SHA-256 digest of the file content, if
+// Digest is set and the file system is an Opener.
+func (node *Node) sha256(opts *Options) (string, bool) {
+	fs, ok := opts.Fs.(Opener)
+	if !opts.Digest || !ok {
+		return "", false
+	}
+	f, err := fs.Open(node.path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// mtreeString encodes the characters of s that can't be used as is in an
+// mtree specification, as an octal escape sequence, e.g: "\040" for space.
+func mtreeString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c >= 0x7f || strings.IndexByte(`\#=*?[`, c) != -1 {
+			fmt.Fprintf(&b, "\\%03o", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
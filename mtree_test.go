@@ -0,0 +1,115 @@
+package tree
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Mock file system that can read files, their content is their path.
+type openFs struct {
+	*MockFs
+}
+
+func (fs openFs) Open(path string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(path)), nil
+}
+
+func TestMTree(t *testing.T) {
+	mtime := time.Unix(1136214245, 5)
+	root := &file{
+		name:    "root",
+		mode:    os.ModeDir | 0755,
+		lastMod: mtime,
+		stat:    &syscall.Stat_t{Uid: 1, Gid: 1},
+		files: []*file{
+			{name: "a b", size: 1, mode: 0644, lastMod: mtime, stat: &syscall.Stat_t{Uid: 1, Gid: 1}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:    "c",
+				mode:    os.ModeDir | 0700,
+				lastMod: mtime,
+				stat:    &syscall.Stat_t{Uid: 1, Gid: 1},
+				files: []*file{
+					{name: "d", size: 2, mode: 0600, lastMod: mtime, stat: &syscall.Stat_t{Uid: 1, Gid: 2}},
+					{name: "e#", size: 3, mode: 0600, lastMod: mtime, stat: &syscall.Stat_t{Uid: 1, Gid: 2}},
+				},
+			},
+			{name: "x", size: 4, mode: 0755, lastMod: mtime, stat: &syscall.Stat_t{Uid: 1, Gid: 1}},
+			{name: "y", size: 5, mode: 0644, lastMod: mtime, stat: &syscall.Stat_t{Uid: 1, Gid: 1}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, MTree: true, Now: mtime.UTC()}
+	inf := New(root.name)
+	d, f := inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	p.End(d, f)
+	expected := `#	   tree: root
+#	   date: Mon Jan  2 15:04:05 2006
+
+# .
+/set type=file uid=1 gid=1 mode=0644
+.               type=dir mode=0755 time=1136214245.000000005
+    a\040b          size=1 time=1136214245.000000005
+    x               mode=0755 size=4 time=1136214245.000000005
+    y               size=5 time=1136214245.000000005
+    # bad: stat failed
+
+# ./c
+/set type=file uid=1 gid=2 mode=0600
+c               type=dir gid=1 mode=0700 time=1136214245.000000005
+    d               size=2 time=1136214245.000000005
+    e\043           size=3 time=1136214245.000000005
+# ./c
+..
+..
+`
+	if !out.equal(expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+
+	opts = &Options{Fs: openFs{fs}, OutFile: out, MTree: true, Digest: true, DeepLevel: 1}
+	inf = New(root.name)
+	inf.Visit(opts)
+	inf.Print(opts)
+	// sha256("root/y")
+	if digest := "sha256digest=d95cb79f349e25cbd2fa866a68a172cc7e6394b1fa7afcf644a4c2441f9dfef3"; !strings.Contains(out.str, digest) {
+		t.Errorf("missing %s in:\n%s", digest, out.str)
+	}
+	out.clear()
+}
+
+func TestMTreeRootError(t *testing.T) {
+	fs := NewMemFs()
+	fs.Mkdir("root", 0755)
+	tests := []struct {
+		name     string
+		root     string
+		opts     *Options
+		expected string
+	}{
+		{"missing", "missing", &Options{}, "# .: file does not exist\n"},
+		{"pattern", "root", &Options{Pattern: "[a"}, "# .: syntax error in pattern\n"},
+	}
+	for _, test := range tests {
+		test.opts.Fs = fs
+		test.opts.OutFile = out
+		test.opts.MTree = true
+		test.opts.Now = time.Unix(1136214245, 0).UTC()
+		inf := New(test.root)
+		inf.Visit(test.opts)
+		inf.Print(test.opts)
+		expected := "#\t   tree: " + test.root + "\n#\t   date: Mon Jan  2 15:04:05 2006\n\n" + test.expected
+		if !out.equal(expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, expected)
+		}
+		out.clear()
+	}
+}
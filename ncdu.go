@@ -0,0 +1,135 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ncduPrinter prints the tree in the JSON export format of ncdu, so it can
+// be browsed with `ncdu -f`. The format has a single root, and therefore
+// only the first root is printed.
+//
+//	[1,2,{"progname":"tree","timestamp":1136214245},
+//	[{"name":"/abs/root","asize":4096,"dsize":4096,"dev":2049,"ino":2},
+//	 {"name":"a","asize":12,"dsize":4096,"ino":3}]
+//	]
+type ncduPrinter struct{}
+
+func (ncduPrinter) begin(opts *Options) {
+	t := opts.Now
+	if t.IsZero() {
+		t = time.Now()
+	}
+	fmt.Fprintf(opts.OutFile, "[1,2,{\"progname\":\"tree\",\"timestamp\":%d},\n", t.Unix())
+}
+
+func (ncduPrinter) print(node *Node, i int, opts *Options) {
+	if i == 0 {
+		node.printNCDU("", 0, opts)
+		fmt.Fprintln(opts.OutFile)
+	}
+}
+
+func (ncduPrinter) end(dirs, files int, opts *Options) { fmt.Fprintln(opts.OutFile, "]") }
+
+// printNCDU prints the node as an ncdu entry. dev is the device of the
+// parent directory, as the device is written only if it's different.
+func (node *Node) printNCDU(indent string, dev uint64, opts *Options) {
+	w := opts.OutFile
+	info := node.ncduInfo(dev, opts)
+	if node.FileInfo == nil || !node.IsDir() {
+		fmt.Fprint(w, indent, info)
+		return
+	}
+	fmt.Fprint(w, indent, "[", info)
+	_, _, dev, _, _ = getStat(node)
+	for _, nnode := range node.nodes {
+		fmt.Fprintln(w, ",")
+		nnode.printNCDU(indent+" ", dev, opts)
+	}
+	fmt.Fprint(w, "]")
+}
+
+// ncduInfo returns the ncdu information object of the node.
+func (node *Node) ncduInfo(dev uint64, opts *Options) string {
+	name := filepath.Base(node.path)
+	if node.depth == 0 {
+		name = node.abs()
+	}
+	fields := []string{`"name":` + jsonString(name)}
+	if node.FileInfo == nil {
+		return "{" + strings.Join(append(fields, `"read_error":true`), ",") + "}"
+	}
+	ok, inode, device, _, _ := getStat(node)
+	asize := node.Size()
+	dsize := asize
+	if ok, blocks, nlink := getUsage(node); ok {
+		dsize = int64(blocks) * 512
+		if nlink > 1 && !node.IsDir() {
+			fields = append(fields, `"hlnkc":true`)
+		}
+	}
+	fields = append(fields, fmt.Sprintf(`"asize":%d,"dsize":%d`, asize, dsize))
+	if ok {
+		if node.depth == 0 || device != dev {
+			fields = append(fields, fmt.Sprintf(`"dev":%d`, device))
+		}
+		fields = append(fields, fmt.Sprintf(`"ino":%d`, inode))
+	}
+	// Extended information, as written by `ncdu -e`
+	if ok, uid, gid, _, _ := getOwner(node); ok {
+		if opts.ShowUid {
+			fields = append(fields, fmt.Sprintf(`"uid":%d`, uid))
+		}
+		if opts.ShowGid {
+			fields = append(fields, fmt.Sprintf(`"gid":%d`, gid))
+		}
+	}
+	if opts.FileMode {
+		fields = append(fields, fmt.Sprintf(`"mode":%d`, unixMode(node.Mode())))
+	}
+	if opts.LastMod {
+		fields = append(fields, fmt.Sprintf(`"mtime":%d`, node.ModTime().Unix()))
+	}
+	if node.err != nil {
+		fields = append(fields, `"read_error":true`)
+	}
+	if !node.IsDir() && !node.Mode().IsRegular() {
+		fields = append(fields, `"notreg":true`)
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+// unixMode converts m to the mode bits of stat(2), as st_mode holds them.
+func unixMode(m os.FileMode) uint32 {
+	mode := uint32(m.Perm())
+	switch {
+	case m&os.ModeDir != 0:
+		mode |= 0040000
+	case m&os.ModeSymlink != 0:
+		mode |= 0120000
+	case m&os.ModeNamedPipe != 0:
+		mode |= 0010000
+	case m&os.ModeSocket != 0:
+		mode |= 0140000
+	case m&os.ModeCharDevice != 0:
+		mode |= 0020000
+	case m&os.ModeDevice != 0:
+		mode |= 0060000
+	default:
+		mode |= 0100000
+	}
+	if m&os.ModeSetuid != 0 {
+		mode |= 04000
+	}
+	if m&os.ModeSetgid != 0 {
+		mode |= 02000
+	}
+	if m&os.ModeSticky != 0 {
+		mode |= 01000
+	}
+	return mode
+}
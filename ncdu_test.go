@@ -0,0 +1,55 @@
+package tree
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNCDU(t *testing.T) {
+	root := &file{
+		name: "root",
+		size: 4096,
+		mode: os.ModeDir | 0755,
+		stat: &syscall.Stat_t{Ino: 1, Dev: 7, Blocks: 8, Nlink: 3},
+		files: []*file{
+			{name: "a", size: 100, mode: 0644, stat: &syscall.Stat_t{Ino: 2, Dev: 7, Blocks: 8, Nlink: 2}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:  "c",
+				size:  4096,
+				mode:  os.ModeDir | 0700,
+				stat:  &syscall.Stat_t{Ino: 3, Dev: 9, Blocks: 8, Nlink: 2},
+				files: []*file{{name: "d", mode: os.ModeSymlink | 0777, stat: &syscall.Stat_t{Ino: 4, Dev: 9}}},
+			},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, NCDU: true, FileMode: true, Now: time.Unix(1136214245, 0)}
+	inf := New(root.name)
+	d, f := inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	p.Print(New("ignored"))
+	p.End(d, f)
+	abs, _ := filepath.Abs("root")
+	expected := `[1,2,{"progname":"tree","timestamp":1136214245},
+[{"name":"` + abs + `","asize":4096,"dsize":4096,"dev":7,"ino":1,"mode":16877},
+ {"name":"a","hlnkc":true,"asize":100,"dsize":4096,"ino":2,"mode":33188},
+ [{"name":"c","asize":4096,"dsize":4096,"dev":9,"ino":3,"mode":16832},
+  {"name":"d","asize":0,"dsize":0,"ino":4,"mode":41471,"notreg":true}],
+ {"name":"bad","read_error":true}]
+]
+`
+	if !out.equal(expected) {
+		t.Errorf("got:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	var v []interface{}
+	if err := json.Unmarshal([]byte(out.str), &v); err != nil {
+		t.Errorf("invalid JSON output: %v", err)
+	}
+	out.clear()
+}
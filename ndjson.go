@@ -0,0 +1,137 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ndjsonPrinter writes one JSON object per line for each node, followed by
+// the report. The nodes can also be streamed as they are visited, see:
+// Printer.Visit.
+type ndjsonPrinter struct{}
+
+func (ndjsonPrinter) begin(opts *Options) {}
+
+func (ndjsonPrinter) print(node *Node, i int, opts *Options) { node.printNDJSON(opts) }
+
+func (ndjsonPrinter) end(dirs, files int, opts *Options) {
+	if opts.NoReport {
+		return
+	}
+	report := fmt.Sprintf(`{"type":"report","directories":%d`, dirs)
+	if !opts.DirsOnly {
+		report += fmt.Sprintf(`,"files":%d`, files)
+	}
+	fmt.Fprintln(opts.OutFile, report+"}")
+}
+
+// printNDJSON writes a visited node, and the nodes under it.
+func (node *Node) printNDJSON(opts *Options) {
+	fmt.Fprintln(opts.OutFile, node.ndjson(opts))
+	if node.err == nil && node.Mode()&os.ModeSymlink != 0 {
+		node.link(opts)
+	}
+	for _, nnode := range node.nodes {
+		nnode.printNDJSON(opts)
+	}
+}
+
+// errDirSizeStream is the error of the directories that are filtered by
+// size when they are streamed.
+var errDirSizeStream = errors.New("directories can't be filtered by size when they are streamed")
+
+// stream visits the node like Visit does, but writes each node as soon as
+// it's walked, in the same order Print would have. Only the directories on
+// the path to the current node are kept in memory.
+//
+// Since the nodes are written before their children are visited, the size
+// of a directory is the size of the entry itself, and not its recursive
+// size.
+func (node *Node) stream(opts *Options) (dirs, files int) {
+	s := &streamer{opts: opts}
+	node.stat(opts)
+	if node.err == nil && opts.dirSizeFilter() {
+		node.err = errDirSizeStream
+	}
+	dirs, files = node.walk(opts, s)
+	s.leave(node, false)
+	s.flush()
+	return
+}
+
+// streamer is the visitor that writes the walked nodes. When pruning, the
+// lines of directories and errors are held until a file is found under
+// them, or dropped if the directory is pruned.
+type streamer struct {
+	opts *Options
+	buf  []string
+	// marks are the lengths of buf when the nodes that are being walked
+	// were entered.
+	marks []int
+}
+
+func (s *streamer) enter(node *Node) {
+	opts := s.opts
+	s.marks = append(s.marks, len(s.buf))
+	s.emit(node)
+	// Follow symbolic links like directories
+	if opts.FollowLink && node.err == nil && node.Mode()&os.ModeSymlink != 0 {
+		if _, path, fi := node.target(opts); fi != nil && fi.IsDir() {
+			node.follow(path, fi, opts, s)
+		}
+	}
+}
+
+func (s *streamer) leave(node *Node, pruned bool) {
+	mark := s.marks[len(s.marks)-1]
+	s.marks = s.marks[:len(s.marks)-1]
+	if pruned {
+		s.buf = s.buf[:mark]
+	}
+	// The written nodes are not kept in the tree
+	node.nodes = nil
+}
+
+// emit writes the node, or holds it if it's not known yet to be listed.
+func (s *streamer) emit(node *Node) {
+	line := node.ndjson(s.opts)
+	if s.opts.Prune && (node.err != nil || node.IsDir()) {
+		s.buf = append(s.buf, line)
+		return
+	}
+	s.flush()
+	fmt.Fprintln(s.opts.OutFile, line)
+}
+
+// flush writes the held lines.
+func (s *streamer) flush() {
+	for _, line := range s.buf {
+		fmt.Fprintln(s.opts.OutFile, line)
+	}
+	s.buf = s.buf[:0]
+}
+
+// ndjson returns the node encoded as a single line of JSON.
+func (node *Node) ndjson(opts *Options) string {
+	fields := []string{
+		`"type":"` + node.kind() + `"`,
+		`"path":` + jsonString(node.path),
+		fmt.Sprintf(`"depth":%d`, node.depth),
+	}
+	if node.FileInfo != nil {
+		fields = append(fields, node.jsonFields(opts)...)
+		if node.Mode()&os.ModeSymlink != 0 {
+			target, path, fi := node.target(opts)
+			fields = append(fields, `"target":`+jsonString(target))
+			if opts.FollowLink && fi != nil && fi.IsDir() && node.visited(path) {
+				fields = append(fields, `"error":"recursive, not followed"`)
+			}
+		}
+	}
+	if node.err != nil {
+		fields = append(fields, `"error":`+jsonString(node.errMsg()))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
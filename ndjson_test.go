@@ -0,0 +1,108 @@
+package tree
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// paths returns the paths of a visited tree, in the order Print prints them.
+func (node *Node) paths() (paths []string) {
+	paths = append(paths, node.path)
+	for _, nnode := range node.nodes {
+		paths = append(paths, nnode.paths()...)
+	}
+	return
+}
+
+func TestNDJSON(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "b", size: 2},
+			{name: "bad"}, // stat fails on this file
+			{name: "c", files: []*file{{name: "d", size: 4}, {name: ".e"}}},
+			{name: "f", files: []*file{}},
+			{name: "a", size: 1},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	opts := &Options{Fs: fs, OutFile: out, NDJSON: true, ByteSize: true}
+	expected := `{"type":"directory","path":"root","depth":0,"size":0}
+{"type":"file","path":"root/a","depth":1,"size":1}
+{"type":"file","path":"root/b","depth":1,"size":2}
+{"type":"directory","path":"root/c","depth":1,"size":0}
+{"type":"file","path":"root/c/d","depth":2,"size":4}
+{"type":"directory","path":"root/f","depth":1,"size":0}
+{"type":"file","path":"root/bad","depth":1,"error":"stat failed"}
+{"type":"report","directories":2,"files":3}
+`
+	// The visited tree is written like the streamed one.
+	inf := New(root.name)
+	d, f := inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	p.End(d, f)
+	if !out.equal(expected) {
+		t.Errorf("visit:\ngot:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	out.clear()
+	inf = New(root.name)
+	p = NewPrinter(opts)
+	p.End(p.Visit(inf))
+	if !out.equal(expected) {
+		t.Errorf("stream:\ngot:\n%+v\nexpected:\n%+v", out.str, expected)
+	}
+	if inf.nodes != nil {
+		t.Errorf("streamed nodes should not be kept in the tree")
+	}
+	out.clear()
+}
+
+// The streamed output should list the same nodes as Visit, for any options.
+func TestNDJSONOptions(t *testing.T) {
+	root := &file{
+		name: "root",
+		files: []*file{
+			{name: "a", size: 50},
+			{name: "b", size: 50},
+			{name: "bad", size: 50}, // stat fails on this file
+			{
+				name: "c",
+				files: []*file{
+					{name: "d", size: 50},
+					{name: "e", size: 50},
+					{name: ".f", size: 0},
+					{name: "g", files: []*file{{name: "h", size: 50}, {name: "i", size: 50}}},
+					{name: "k", size: 50},
+				},
+			},
+			{name: "j", size: 50},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range append(listTests, sortTests...) {
+		opts := *test.opts
+		inf := New(root.name)
+		d, f := inf.Visit(&opts)
+		expected := inf.paths()
+
+		opts.NDJSON = true
+		sd, sf := NewPrinter(&opts).Visit(New(root.name))
+		if sd != d || sf != f {
+			t.Errorf("%s: wrong count:\ngot:\n%d, %d\nexpected:\n%d, %d", test.name, sd, sf, d, f)
+		}
+		var actual []string
+		for _, line := range strings.Split(strings.TrimSpace(out.str), "\n") {
+			var v struct{ Path string }
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				t.Fatalf("%s: invalid JSON line %q: %v", test.name, line, err)
+			}
+			actual = append(actual, v.Path)
+		}
+		if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s:\ngot:\n%s\nexpected:\n%s", test.name, strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+		}
+		out.clear()
+	}
+}
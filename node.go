@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/user"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,11 +16,19 @@ import (
 // contains FileInfo, and its childs
 type Node struct {
 	os.FileInfo
+	fs     Fs
 	path   string
 	depth  int
 	err    error
 	nodes  Nodes
 	vpaths map[string]bool
+	ignore ignoreRules
+	// patterns are the compiled patterns of the walk, that are shared by
+	// the nodes of the tree.
+	patterns *patterns
+	// rsize is the recursive size of a directory whose files are left out,
+	// see: filterDirs.
+	rsize *recursiveSize
 }
 
 // List of nodes
@@ -36,6 +42,43 @@ type Fs interface {
 	ReadDir(path string) ([]string, error)
 }
 
+// Opener is implemented by file systems that can read the content of
+// files. It's used to compute file digests.
+type Opener interface {
+	Open(path string) (io.ReadCloser, error)
+}
+
+// Readlinker is implemented by file systems that have their own symbolic
+// links, e.g: archives. Readlink returns the target of the link as it was
+// written, and the path it resolves to in the file system.
+type Readlinker interface {
+	Readlink(path string) (target, resolved string, err error)
+}
+
+// Joiner is implemented by file systems whose paths are not file paths,
+// e.g: URLs. Join returns the path of the named entry of the directory.
+type Joiner interface {
+	Join(dir, name string) string
+}
+
+// SysInfo is implemented by the values returned by the Sys method of the
+// FileInfos of file systems that have their own owners and times, e.g:
+// archives.
+type SysInfo interface {
+	// Owner returns the owner ids of the file, and their names.
+	Owner() (uid, gid uint64, user, group string)
+	// Times returns the access and status change times of the file, that
+	// are zero if they are unknown.
+	Times() (atime, ctime time.Time)
+}
+
+// PackedSizer is implemented by the values returned by the Sys method of
+// the FileInfos of compressed archives, e.g: zip archives.
+type PackedSizer interface {
+	// PackedSize returns the compressed size of the file in the archive.
+	PackedSize() int64
+}
+
 // Options store the configuration for specific tree.
 // Note, that 'Fs', and 'OutFile' are required (OutFile can be os.Stdout).
 type Options struct {
@@ -48,10 +91,32 @@ type Options struct {
 	IgnoreCase bool
 	FollowLink bool
 	DeepLevel  int
-	Pattern    string
-	IPattern   string
-	MatchDirs  bool
-	Prune      bool
+	// Pattern and IPattern are wildcard patterns, e.g: "*.go|*.mod", or
+	// regular expressions if Regex is set. Patterns and IPatterns are
+	// more of them, e.g: of repeated flags, or of pattern files.
+	Pattern   string
+	IPattern  string
+	Patterns  []string
+	IPatterns []string
+	Regex     bool
+	MatchDirs bool
+	Prune     bool
+	// GitIgnore skips the files that are ignored by the .gitignore files,
+	// .git/info/exclude and the global excludes file of git, and the .git
+	// directory. It needs an Fs that is an Opener.
+	GitIgnore bool
+	// MinSize and MaxSize, if they are set, leave out the files whose size
+	// is out of range. With DirsOnly, the directories are filtered by their
+	// recursive size instead, that counts the files down to DeepLevel. It's
+	// not possible when the nodes are streamed, see: Printer.Visit.
+	MinSize int64
+	MaxSize int64
+	// Newer and Older, if they are set, leave out the files whose time is
+	// not after Newer, or not before Older. TimeField is the time that is
+	// compared: "mtime" (the default), "ctime" or "atime".
+	Newer     time.Time
+	Older     time.Time
+	TimeField string
 	// File
 	ByteSize bool
 	UnitSize bool
@@ -62,6 +127,12 @@ type Options struct {
 	Quotes   bool
 	Inodes   bool
 	Device   bool
+	// CompressedSize prints the compressed size of the files that have it,
+	// see: PackedSizer.
+	CompressedSize bool
+	// ShowLayer prints the layer the files come from, if Fs is made of
+	// layers, e.g: "[layer: site]".
+	ShowLayer bool
 	// Sort
 	NoSort    bool
 	VerSort   bool
@@ -74,9 +145,36 @@ type Options struct {
 	// Graphics
 	NoIndent bool
 	Colorize bool
+	// Output
+	NoReport bool
+	JSON     bool
+	XML      bool
+	HTML     bool
+	NDJSON   bool
+	CSV      bool
+	TSV      bool
+	DOT      bool
+	Markdown bool
+	Mermaid  bool
+	YAML     bool
+	NCDU     bool
+	MTree    bool
+	// Digest adds the SHA-256 digest of the files to the mtree output, if
+	// Fs is an Opener.
+	Digest bool
+	// Fenced prints the Markdown output as the text tree in a code block,
+	// and the Mermaid output in a "mermaid" code block.
+	Fenced bool
+	// BaseHREF is the prefix of the links in the HTML, Markdown and
+	// Mermaid outputs.
+	BaseHREF string
+	// Title of the HTML output, defaults to "Directory Tree".
+	Title string
 	// Color defaults to ANSIColor()
 	Color func(*Node, string) string
 	Now   time.Time
+	// The compiled patterns, see: Compile.
+	patterns *patterns
 }
 
 func (opts *Options) color(node *Node, s string) string {
@@ -94,20 +192,59 @@ func New(path string) *Node {
 
 // Visit all files under the given node.
 func (node *Node) Visit(opts *Options) (dirs, files int) {
-	// visited paths
-	if path, err := filepath.Abs(node.path); err == nil {
-		path = filepath.Clean(path)
-		node.vpaths[path] = true
+	node.stat(opts)
+	dirs, files = node.walk(opts, collector{})
+	// "dirs only" option with size filters, the files are left out once the
+	// size of all directories is known
+	if node.depth == 0 && opts.dirSizeFilter() {
+		return node.filterDirs(opts), 0
+	}
+	return
+}
+
+// stat stats the root node, once the patterns are compiled.
+func (node *Node) stat(opts *Options) {
+	var err error
+	if node.patterns, err = opts.compiled(); err != nil {
+		node.err = err
+		return
 	}
-	// stat
 	fi, err := opts.Fs.Stat(node.path)
 	if err != nil {
 		node.err = err
 		return
 	}
 	node.FileInfo = fi
-	if !fi.IsDir() {
-		return 0, 1
+}
+
+// visitor is notified of the walked nodes, in the order Print lists them.
+type visitor interface {
+	// enter is called with each listed node, before the files of a
+	// directory are walked.
+	enter(node *Node)
+	// leave is called once the node and its files were walked. pruned is
+	// true if the node is an empty directory, left out by Prune.
+	leave(node *Node, pruned bool)
+}
+
+// collector is the visitor of Visit, the nodes are only kept in the tree.
+type collector struct{}
+
+func (collector) enter(node *Node) {}
+
+func (collector) leave(node *Node, pruned bool) {}
+
+// walk walks the stat'ed node, and the files under it into node.nodes.
+func (node *Node) walk(opts *Options, v visitor) (dirs, files int) {
+	node.fs = opts.Fs
+	// visited paths
+	node.vpaths[node.abs()] = true
+	if node.err != nil || !node.IsDir() {
+		v.enter(node)
+		if node.err == nil {
+			files++
+		}
+		return
 	}
 	// increase dirs only if it's a dir, but not the root.
 	if node.depth != 0 {
@@ -115,80 +252,106 @@ func (node *Node) Visit(opts *Options) (dirs, files int) {
 	}
 	// DeepLevel option
 	if opts.DeepLevel > 0 && opts.DeepLevel <= node.depth {
+		v.enter(node)
 		return
 	}
-	// MatchDirs option
-	var dirMatch bool
-	if node.depth != 0 && opts.MatchDirs {
-		// then disable prune and pattern for immediate children
-		if opts.Pattern != "" {
-			dirMatch = node.match(opts.Pattern, opts)
-		} else if opts.IPattern != "" && node.match(opts.IPattern, opts) {
-			return
-		}
+	// MatchDirs option, or directory patterns
+	dirMatch := node.depth != 0 && node.patterns.include.match(node, opts)
+	names := node.readDir(opts)
+	v.enter(node)
+	if node.err != nil {
+		return
 	}
+	d, f := node.walkDir(names, dirMatch, opts, v)
+	return dirs + d, files + f
+}
+
+// readDir returns the names of the files in the directory node, and reads
+// its ignore rules.
+func (node *Node) readDir(opts *Options) []string {
 	names, err := opts.Fs.ReadDir(node.path)
 	if err != nil {
 		node.err = err
-		return
+		return nil
+	}
+	if opts.GitIgnore {
+		node.ignore = node.gitIgnore(names, opts)
 	}
-	node.nodes = make(Nodes, 0)
+	return names
+}
+
+// walkDir walks the given files of a directory node. The files are stat'ed,
+// filtered and sorted before any of them is walked.
+// dirMatch is true if the directory matched the pattern.
+func (node *Node) walkDir(names []string, dirMatch bool, opts *Options, v visitor) (dirs, files int) {
+	node.nodes = make(Nodes, 0, len(names))
 	for _, name := range names {
 		// "all" option
 		if !opts.All && strings.HasPrefix(name, ".") {
 			continue
 		}
 		nnode := &Node{
-			path:   filepath.Join(node.path, name),
-			depth:  node.depth + 1,
-			vpaths: node.vpaths,
+			path:     opts.join(node.path, name),
+			depth:    node.depth + 1,
+			vpaths:   node.vpaths,
+			ignore:   node.ignore,
+			patterns: node.patterns,
 		}
-		d, f := nnode.Visit(opts)
-		if nnode.err == nil {
-			if nnode.IsDir() {
-				// "prune" option, hide empty directories
-				if opts.Prune && f == 0 {
-					continue
-				}
-				if opts.MatchDirs && opts.IPattern != "" && nnode.match(opts.IPattern, opts) {
-					continue
-				}
-			} else {
-				// "dirs only" option
-				if opts.DirsOnly {
-					continue
-				}
-				// Pattern matching
-				if !dirMatch && opts.Pattern != "" && !nnode.match(opts.Pattern, opts) {
-					continue
-				}
-				// IPattern matching
-				if opts.IPattern != "" && nnode.match(opts.IPattern, opts) {
-					continue
-				}
-			}
+		// "gitignore" option, ignored files are not visited nor counted
+		if opts.GitIgnore && nnode.ignored(opts) {
+			continue
+		}
+		if fi, err := opts.Fs.Stat(nnode.path); err != nil {
+			nnode.err = err
+		} else if nnode.FileInfo = fi; nnode.skip(dirMatch, opts) {
+			continue
 		}
 		node.nodes = append(node.nodes, nnode)
-		dirs, files = dirs+d, files+f
 	}
 	// Sorting
 	if !opts.NoSort {
 		node.sort(opts)
 	}
+	nodes := node.nodes[:0]
+	for _, nnode := range node.nodes {
+		d, f := nnode.walk(opts, v)
+		// "prune" option, hide empty directories
+		pruned := opts.Prune && nnode.err == nil && nnode.IsDir() && f == 0
+		v.leave(nnode, pruned)
+		if pruned {
+			continue
+		}
+		nodes = append(nodes, nnode)
+		dirs, files = dirs+d, files+f
+	}
+	node.nodes = nodes
 	return
 }
 
-func (node *Node) match(pattern string, opt *Options) bool {
-	var prefix string
-	if opt.IgnoreCase {
-		prefix = "(?i)"
+// skip reports whether a visited node should be left out of its parent
+// listing. dirMatch is true if the parent directory matched the pattern.
+func (node *Node) skip(dirMatch bool, opts *Options) bool {
+	if node.IsDir() {
+		return node.patterns.exclude.match(node, opts)
+	}
+	// "dirs only" option, see: filterDirs
+	if opts.DirsOnly {
+		return !opts.dirSizeFilter()
+	}
+	// Pattern matching
+	if !dirMatch && node.patterns.include != nil && !node.patterns.include.match(node, opts) {
+		return true
 	}
-	search := node.Name()
-	if strings.Contains(pattern, "*") {
-		search = node.path
+	// IPattern matching
+	if node.patterns.exclude.match(node, opts) {
+		return true
 	}
-	re, err := regexp.Compile(prefix + pattern)
-	return err == nil && re.FindString(search) != ""
+	// Size filters
+	if !opts.inSizeRange(node.Size()) {
+		return true
+	}
+	// Time filters
+	return opts.timeFilter() && !opts.inTimeRange(opts.FileTime(node.FileInfo))
 }
 
 func (node *Node) sort(opts *Options) {
@@ -224,9 +387,19 @@ func (node *Node) Path() string {
 }
 
 // Print nodes based on the given configuration.
-func (node *Node) Print(opts *Options) { node.print("", opts) }
+// To print several trees with a report, use Printer instead.
+func (node *Node) Print(opts *Options) { opts.printer().print(node, 0, opts) }
+
+// recursiveSize is the result of dirRecursiveSize.
+type recursiveSize struct {
+	size int64
+	err  error
+}
 
 func dirRecursiveSize(opts *Options, node *Node) (size int64, err error) {
+	if node.rsize != nil {
+		return node.rsize.size, node.rsize.err
+	}
 	if opts.DeepLevel > 0 && node.depth >= opts.DeepLevel {
 		err = errors.New("Depth too high")
 	}
@@ -252,20 +425,72 @@ func dirRecursiveSize(opts *Options, node *Node) (size int64, err error) {
 
 func (node *Node) print(indent string, opts *Options) {
 	if node.err != nil {
-		err := node.err.Error()
-		if msgs := strings.Split(err, ": "); len(msgs) > 1 {
-			err = msgs[1]
-		}
 		name := node.path
 		if !opts.FullPath {
 			name = filepath.Base(name)
 		}
-		fmt.Fprintf(opts.OutFile, "%s [%s]\n", name, err)
+		fmt.Fprintf(opts.OutFile, "%s [%s]\n", name, node.errMsg())
 		return
 	}
+	// Print properties
+	if props := node.props(opts); len(props) > 0 {
+		fmt.Fprintf(opts.OutFile, "[%s]  ", strings.Join(props, " "))
+	}
+	// name/path
+	var name string
+	if node.depth == 0 || opts.FullPath {
+		name = node.path
+	} else {
+		name = node.Name()
+	}
+	// Quotes
+	if opts.Quotes {
+		name = fmt.Sprintf("\"%s\"", name)
+	}
+	// Colorize
+	if opts.Colorize {
+		name = opts.color(node, name)
+	}
+	// IsSymlink
+	if node.Mode()&os.ModeSymlink == os.ModeSymlink {
+		vtarget, fi, recursive := node.link(opts)
+		if opts.Colorize && fi != nil {
+			vtarget = opts.color(&Node{FileInfo: fi, path: vtarget}, vtarget)
+		}
+		name = fmt.Sprintf("%s -> %s", name, vtarget)
+		if recursive {
+			name += " [recursive, not followed]"
+		}
+	}
+	// Layer
+	if layer := node.Layer(); opts.ShowLayer && layer != "" {
+		name += fmt.Sprintf(" [layer: %s]", layer)
+	}
+	// Print file details
+	// the main idea of the print logic came from here: github.com/campoy/tools/tree
+	fmt.Fprintln(opts.OutFile, name)
+	add := "│   "
+	for i, nnode := range node.nodes {
+		if opts.NoIndent {
+			add = ""
+		} else {
+			if i == len(node.nodes)-1 {
+				fmt.Fprintf(opts.OutFile, indent+"└── ")
+				add = "    "
+			} else {
+				fmt.Fprintf(opts.OutFile, indent+"├── ")
+			}
+		}
+		nnode.print(indent+add, opts)
+	}
+}
+
+// props returns the file properties enabled by the options, formatted for
+// the text output.
+func (node *Node) props(opts *Options) (props []string) {
 	if !node.IsDir() {
-		var props []string
-		ok, inode, device, uid, gid := getStat(node)
+		ok, inode, device, _, _ := getStat(node)
+		owned, _, gid, user, _ := getOwner(node)
 		// inodes
 		if ok && opts.Inodes {
 			props = append(props, fmt.Sprintf("%d", inode))
@@ -279,17 +504,12 @@ func (node *Node) print(indent string, opts *Options) {
 			props = append(props, node.Mode().String())
 		}
 		// Owner/Uid
-		if ok && opts.ShowUid {
-			uidStr := strconv.Itoa(int(uid))
-			if u, err := user.LookupId(uidStr); err != nil {
-				props = append(props, fmt.Sprintf("%-8s", uidStr))
-			} else {
-				props = append(props, fmt.Sprintf("%-8s", u.Username))
-			}
+		if owned && opts.ShowUid {
+			props = append(props, fmt.Sprintf("%-8s", user))
 		}
 		// Gorup/Gid
 		// TODO: support groupname
-		if ok && opts.ShowGid {
+		if owned && opts.ShowGid {
 			gidStr := strconv.Itoa(int(gid))
 			props = append(props, fmt.Sprintf("%-4s", gidStr))
 		}
@@ -303,31 +523,24 @@ func (node *Node) print(indent string, opts *Options) {
 			}
 			props = append(props, size)
 		}
-		// Last modification
-		if opts.LastMod {
-			t := opts.Now
-			if t.IsZero() {
-				t = time.Now()
-			}
-
-			format := "Jan 02 15:04"
-			if node.ModTime().Year() != t.Year() {
-				format = "Jan 02  2006"
+		// Compressed size
+		if size, ok := node.compressedSize(); ok && opts.CompressedSize {
+			if opts.UnitSize {
+				props = append(props, fmt.Sprintf("%4s", formatBytes(size)))
+			} else {
+				props = append(props, fmt.Sprintf("%11d", size))
 			}
-
-			props = append(props, node.ModTime().Format(format))
 		}
-		// Print properties
-		if len(props) > 0 {
-			fmt.Fprintf(opts.OutFile, "[%s]  ", strings.Join(props, " "))
+		// Last modification
+		if opts.LastMod {
+			props = append(props, node.modTime(opts))
 		}
 	} else {
-		var props []string
 		// Size
 		if opts.ByteSize || opts.UnitSize {
 			var size string
-			rsize, err := dirRecursiveSize(opts, node)
-			if err != nil && rsize <= 0 {
+			rsize, ok := node.size(opts)
+			if !ok {
 				if opts.UnitSize {
 					size = "????"
 				} else {
@@ -340,73 +553,104 @@ func (node *Node) print(indent string, opts *Options) {
 			}
 			props = append(props, size)
 		}
-		// Print properties
-		if len(props) > 0 {
-			fmt.Fprintf(opts.OutFile, "[%s]  ", strings.Join(props, " "))
-		}
 	}
-	// name/path
-	var name string
-	if node.depth == 0 || opts.FullPath {
-		name = node.path
-	} else {
-		name = node.Name()
+	return
+}
+
+// link resolves the target of a symbolic link node. It returns the target
+// as it was written, and its FileInfo if the target exists.
+// If FollowLink is set, and the target is a directory, it is walked into
+// node.nodes, unless it was already visited, in which case recursive is true.
+func (node *Node) link(opts *Options) (target string, fi os.FileInfo, recursive bool) {
+	target, targetPath, fi := node.target(opts)
+	// Follow symbolic links like directories
+	if opts.FollowLink && fi != nil && fi.IsDir() {
+		recursive = !node.follow(targetPath, fi, opts, collector{})
 	}
-	// Quotes
-	if opts.Quotes {
-		name = fmt.Sprintf("\"%s\"", name)
+	return
+}
+
+// follow walks the directory at path, the target of a symbolic link node,
+// into node.nodes as if it was the content of the link. The nodes found
+// under the target are not counted. It reports false if the target was
+// already visited.
+func (node *Node) follow(path string, fi os.FileInfo, opts *Options, v visitor) bool {
+	if node.visited(path) {
+		return false
 	}
-	// Colorize
-	if opts.Colorize {
-		name = opts.color(node, name)
+	inf := &Node{FileInfo: fi, fs: opts.Fs, path: path, depth: node.depth, vpaths: node.vpaths, patterns: node.patterns}
+	inf.vpaths[inf.abs()] = true
+	if names := inf.readDir(opts); inf.err == nil {
+		inf.walkDir(names, false, opts, v)
+		node.nodes = inf.nodes
 	}
-	// IsSymlink
-	if node.Mode()&os.ModeSymlink == os.ModeSymlink {
-		vtarget, err := os.Readlink(node.path)
+	return true
+}
+
+// target returns the target of a symbolic link node as it was written, the
+// path it resolves to, and its FileInfo if the target exists.
+func (node *Node) target(opts *Options) (target, path string, fi os.FileInfo) {
+	if fs, ok := opts.Fs.(Readlinker); ok {
+		target, path, err := fs.Readlink(node.path)
 		if err != nil {
-			vtarget = node.path
+			return node.path, node.path, nil
 		}
-		targetPath, err := filepath.EvalSymlinks(node.path)
+		fi, _ = opts.Fs.Stat(path)
+		return target, path, fi
+	}
+	target, err := os.Readlink(node.path)
+	if err != nil {
+		target = node.path
+	}
+	path, err = filepath.EvalSymlinks(node.path)
+	if err != nil {
+		path = target
+	}
+	fi, _ = opts.Fs.Stat(path)
+	return
+}
+
+// join returns the path of the named entry of the directory, like
+// filepath.Join, unless the file system is a Joiner.
+func (opts *Options) join(dir, name string) string {
+	if fs, ok := opts.Fs.(Joiner); ok {
+		return fs.Join(dir, name)
+	}
+	return filepath.Join(dir, name)
+}
+
+// orphan reports whether the node is a symbolic link to a missing file.
+func (node *Node) orphan() bool {
+	if fs, ok := node.fs.(Readlinker); ok {
+		_, path, err := fs.Readlink(node.path)
 		if err != nil {
-			targetPath = vtarget
-		}
-		fi, err := opts.Fs.Stat(targetPath)
-		if opts.Colorize && fi != nil {
-			vtarget = opts.color(&Node{FileInfo: fi, path: vtarget}, vtarget)
-		}
-		name = fmt.Sprintf("%s -> %s", name, vtarget)
-		// Follow symbolic links like directories
-		if opts.FollowLink {
-			path, err := filepath.Abs(targetPath)
-			if err == nil && fi != nil && fi.IsDir() {
-				if _, ok := node.vpaths[filepath.Clean(path)]; !ok {
-					inf := &Node{FileInfo: fi, path: targetPath}
-					inf.vpaths = node.vpaths
-					inf.Visit(opts)
-					node.nodes = inf.nodes
-				} else {
-					name += " [recursive, not followed]"
-				}
-			}
+			return true
 		}
+		fi, err := node.fs.Stat(path)
+		return err != nil || fi == nil
 	}
-	// Print file details
-	// the main idea of the print logic came from here: github.com/campoy/tools/tree
-	fmt.Fprintln(opts.OutFile, name)
-	add := "│   "
-	for i, nnode := range node.nodes {
-		if opts.NoIndent {
-			add = ""
-		} else {
-			if i == len(node.nodes)-1 {
-				fmt.Fprintf(opts.OutFile, indent+"└── ")
-				add = "    "
-			} else {
-				fmt.Fprintf(opts.OutFile, indent+"├── ")
-			}
-		}
-		nnode.print(indent+add, opts)
+	_, err := filepath.EvalSymlinks(node.path)
+	return err != nil
+}
+
+// visited reports whether the given path was already visited. A path that
+// can't be resolved is considered as visited.
+func (node *Node) visited(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	return node.vpaths[filepath.Clean(path)]
+}
+
+// abs returns the cleaned absolute path of the node, or its path as is if it
+// can't be resolved.
+func (node *Node) abs() string {
+	path, err := filepath.Abs(node.path)
+	if err != nil {
+		return node.path
 	}
+	return filepath.Clean(path)
 }
 
 const (
@@ -157,18 +157,18 @@ var listTests = []treeTest{
 ├── c
 └── j
 `, 1, 3},
-	{"pattern (a|e|i)", &Options{Fs: fs, OutFile: out, Pattern: "(a|e|i)"}, `root
+	{"pattern (a|e|i)", &Options{Fs: fs, OutFile: out, Pattern: "(a|e|i)", Regex: true}, `root
 ├── a
 └── c
     ├── e
     └── g
         └── i
 `, 2, 3},
-	{"pattern (x) + 0 files", &Options{Fs: fs, OutFile: out, Pattern: "(x)"}, `root
+	{"pattern (x) + 0 files", &Options{Fs: fs, OutFile: out, Pattern: "(x)", Regex: true}, `root
 └── c
     └── g
 `, 2, 0},
-	{"ipattern (a|e|i)", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i)"}, `root
+	{"ipattern (a|e|i)", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i)", Regex: true}, `root
 ├── b
 ├── c
 │   ├── d
@@ -177,25 +177,25 @@ var listTests = []treeTest{
 │   └── k
 └── j
 `, 2, 5},
-	{"pattern (A) + ignore-case", &Options{Fs: fs, OutFile: out, Pattern: "(A)", IgnoreCase: true}, `root
+	{"pattern (A) + ignore-case", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Regex: true, IgnoreCase: true}, `root
 ├── a
 └── c
     └── g
 `, 2, 1},
-	{"pattern (A) + ignore-case + prune", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Prune: true, IgnoreCase: true}, `root
+	{"pattern (A) + ignore-case + prune", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Regex: true, Prune: true, IgnoreCase: true}, `root
 └── a
 `, 0, 1},
-	{"pattern (a) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(a)", Prune: true}, `root
+	{"pattern (a) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(a)", Regex: true, Prune: true}, `root
 └── a
 `, 0, 1},
-	{"pattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c)", MatchDirs: true}, `root
+	{"pattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c)", Regex: true, MatchDirs: true}, `root
 └── c
     ├── d
     ├── e
     ├── g
     └── k
 `, 2, 3},
-	{"pattern (c.*) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", MatchDirs: true}, `root
+	{"pattern (c.*) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Regex: true, MatchDirs: true}, `root
 └── c
     ├── d
     ├── e
@@ -204,12 +204,12 @@ var listTests = []treeTest{
     │   └── i
     └── k
 `, 2, 5},
-	{"ipattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(c)", MatchDirs: true}, `root
+	{"ipattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(c)", Regex: true, MatchDirs: true}, `root
 ├── a
 ├── b
 └── j
 `, 0, 3},
-	{"ipattern (g) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(g)", MatchDirs: true}, `root
+	{"ipattern (g) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(g)", Regex: true, MatchDirs: true}, `root
 ├── a
 ├── b
 ├── c
@@ -218,19 +218,19 @@ var listTests = []treeTest{
 │   └── k
 └── j
 `, 1, 6},
-	{"ipattern (a|e|i|h) + matchdirs + prune", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i|h)", MatchDirs: true, Prune: true}, `root
+	{"ipattern (a|e|i|h) + matchdirs + prune", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i|h)", Regex: true, MatchDirs: true, Prune: true}, `root
 ├── b
 ├── c
 │   ├── d
 │   └── k
 └── j
 `, 1, 4},
-	{"pattern (d|e) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(d|e)", Prune: true}, `root
+	{"pattern (d|e) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(d|e)", Regex: true, Prune: true}, `root
 └── c
     ├── d
     └── e
 `, 1, 2},
-	{"pattern (c.*) + matchdirs + prune ", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Prune: true, MatchDirs: true}, `root
+	{"pattern (c.*) + matchdirs + prune ", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Regex: true, Prune: true, MatchDirs: true}, `root
 └── c
     ├── d
     ├── e
@@ -239,6 +239,71 @@ var listTests = []treeTest{
     │   └── i
     └── k
 `, 2, 5},
+	{"wildcard a|e|i", &Options{Fs: fs, OutFile: out, Pattern: "a|e|i"}, `root
+├── a
+└── c
+    ├── e
+    └── g
+        └── i
+`, 2, 3},
+	{"wildcard [a-e] ignored", &Options{Fs: fs, OutFile: out, IPattern: "[a-e]"}, `root
+├── c
+│   ├── g
+│   │   ├── h
+│   │   └── i
+│   └── k
+└── j
+`, 2, 4},
+	{"wildcard c/*", &Options{Fs: fs, OutFile: out, Pattern: "c/*", Prune: true}, `root
+└── c
+    ├── d
+    ├── e
+    └── k
+`, 1, 3},
+	{"wildcard c/**", &Options{Fs: fs, OutFile: out, Pattern: "c/**"}, `root
+└── c
+    ├── d
+    ├── e
+    ├── g
+    │   ├── h
+    │   └── i
+    └── k
+`, 2, 5},
+	{"wildcard **/g/? + prune", &Options{Fs: fs, OutFile: out, Pattern: "**/g/?", Prune: true}, `root
+└── c
+    └── g
+        ├── h
+        └── i
+`, 2, 2},
+	{"wildcard g/ ignored", &Options{Fs: fs, OutFile: out, IPattern: "g/"}, `root
+├── a
+├── b
+├── c
+│   ├── d
+│   ├── e
+│   └── k
+└── j
+`, 1, 6},
+	{"wildcard [^a-c] + ignore-case", &Options{Fs: fs, OutFile: out, Pattern: "[^A-C]", IgnoreCase: true}, `root
+├── c
+│   ├── d
+│   ├── e
+│   ├── g
+│   │   ├── h
+│   │   └── i
+│   └── k
+└── j
+`, 2, 6},
+	{"wildcard patterns", &Options{Fs: fs, OutFile: out, Pattern: "a", Patterns: []string{"c/*", "j"}, IPatterns: []string{"d", "k|j"}}, `root
+├── a
+└── c
+    ├── e
+    └── g
+`, 2, 2},
+	{"invalid wildcard", &Options{Fs: fs, OutFile: out, Pattern: "[a"}, `root [syntax error in pattern]
+`, 0, 0},
+	{"invalid regex", &Options{Fs: fs, OutFile: out, Pattern: "(a", Regex: true}, `root [error parsing regexp]
+`, 0, 0},
 }
 
 func TestSimple(t *testing.T) {
@@ -525,57 +590,57 @@ var errorTests = []treeTest{
 ├── j
 └── bad [stat failed]
 `, 0, 3},
-	{"pattern (a|e|i)", &Options{Fs: fs, OutFile: out, Pattern: "(a|e|i)"}, `root
+	{"pattern (a|e|i)", &Options{Fs: fs, OutFile: out, Pattern: "(a|e|i)", Regex: true}, `root
 ├── a
 └── bad [stat failed]
 `, 0, 1},
-	{"pattern (x) + 0 files", &Options{Fs: fs, OutFile: out, Pattern: "(x)"}, `root
+	{"pattern (x) + 0 files", &Options{Fs: fs, OutFile: out, Pattern: "(x)", Regex: true}, `root
 └── bad [stat failed]
 `, 0, 0},
-	{"ipattern (a|e|i)", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i)"}, `root
+	{"ipattern (a|e|i)", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i)", Regex: true}, `root
 ├── b
 ├── j
 └── bad [stat failed]
 `, 0, 2},
-	{"pattern (A) + ignore-case", &Options{Fs: fs, OutFile: out, Pattern: "(A)", IgnoreCase: true}, `root
+	{"pattern (A) + ignore-case", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Regex: true, IgnoreCase: true}, `root
 ├── a
 └── bad [stat failed]
 `, 0, 1},
-	{"pattern (A) + ignore-case + prune", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Prune: true, IgnoreCase: true}, `root
+	{"pattern (A) + ignore-case + prune", &Options{Fs: fs, OutFile: out, Pattern: "(A)", Regex: true, Prune: true, IgnoreCase: true}, `root
 ├── a
 └── bad [stat failed]
 `, 0, 1},
-	{"pattern (a) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(a)", Prune: true}, `root
+	{"pattern (a) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(a)", Regex: true, Prune: true}, `root
 ├── a
 └── bad [stat failed]
 `, 0, 1},
-	{"pattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c)", MatchDirs: true}, `root
+	{"pattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c)", Regex: true, MatchDirs: true}, `root
 └── bad [stat failed]
 `, 0, 0},
-	{"pattern (c.*) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", MatchDirs: true}, `root
+	{"pattern (c.*) + matchdirs", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Regex: true, MatchDirs: true}, `root
 └── bad [stat failed]
 `, 0, 0},
-	{"ipattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(c)", MatchDirs: true}, `root
+	{"ipattern (c) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(c)", Regex: true, MatchDirs: true}, `root
 ├── a
 ├── b
 ├── j
 └── bad [stat failed]
 `, 0, 3},
-	{"ipattern (g) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(g)", MatchDirs: true}, `root
+	{"ipattern (g) + matchdirs", &Options{Fs: fs, OutFile: out, IPattern: "(g)", Regex: true, MatchDirs: true}, `root
 ├── a
 ├── b
 ├── j
 └── bad [stat failed]
 `, 0, 3},
-	{"ipattern (a|e|i|h) + matchdirs + prune", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i|h)", MatchDirs: true, Prune: true}, `root
+	{"ipattern (a|e|i|h) + matchdirs + prune", &Options{Fs: fs, OutFile: out, IPattern: "(a|e|i|h)", Regex: true, MatchDirs: true, Prune: true}, `root
 ├── b
 ├── j
 └── bad [stat failed]
 `, 0, 2},
-	{"pattern (d|e) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(d|e)", Prune: true}, `root
+	{"pattern (d|e) + prune", &Options{Fs: fs, OutFile: out, Pattern: "(d|e)", Regex: true, Prune: true}, `root
 └── bad [stat failed]
 `, 0, 0},
-	{"pattern (c.*) + matchdirs + prune ", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Prune: true, MatchDirs: true}, `root
+	{"pattern (c.*) + matchdirs + prune ", &Options{Fs: fs, OutFile: out, Pattern: "(c.*)", Regex: true, Prune: true, MatchDirs: true}, `root
 └── bad [stat failed]
 `, 0, 0},
 
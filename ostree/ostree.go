@@ -2,6 +2,7 @@ package ostree
 
 import (
 	"bytes"
+	"io"
 	"os"
 
 	"github.com/a8m/tree"
@@ -29,6 +30,11 @@ func (f *FS) ReadDir(path string) ([]string, error) {
 	return names, nil
 }
 
+// Open a file for reading
+func (f *FS) Open(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
 // Print a tree of the directory
 func Print(dir string) string {
 	b := new(bytes.Buffer)
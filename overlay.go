@@ -0,0 +1,313 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// Whiteout markers of layers, as used by overlay and union file systems.
+// A ".wh.<name>" file hides <name> of the lower layers, and an opaque
+// marker hides all the entries of its directory in the lower layers.
+const (
+	WhiteoutPrefix = ".wh."
+	OpaqueMarker   = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// Layer is a directory of an OverlayFs.
+type Layer struct {
+	// Name of the layer, e.g: "site".
+	Name string
+	// Fs and Root are the file system of the layer and the path of its
+	// directory.
+	Fs   Fs
+	Root string
+}
+
+// OverlayFs is an Fs that merges the directories of several layers, where
+// the files of the upper layers win, like an overlay mount. Whiteout
+// markers are honored, and are not listed. The FileInfo of the files is a
+// LayerInfo, that reports the layer they come from.
+//
+//	fs := &tree.OverlayFs{Root: "etc", Layers: []tree.Layer{
+//		{Name: "defaults", Fs: new(ostree.FS), Root: "defaults/etc"},
+//		{Name: "site", Fs: new(ostree.FS), Root: "site/etc"},
+//	}}
+type OverlayFs struct {
+	// Root is the path of the merged directory.
+	Root string
+	// Layers from the lowest to the uppermost.
+	Layers []Layer
+}
+
+// LayerInfo is implemented by the FileInfo of the files of an Fs that is
+// made of layers, like OverlayFs.
+type LayerInfo interface {
+	os.FileInfo
+	// Layer returns the name of the layer the file comes from.
+	Layer() string
+}
+
+// Layer returns the name of the layer the node comes from, if its Fs is
+// made of layers. It can be used to color the nodes by their origin.
+func (node *Node) Layer() string {
+	if fi, ok := node.FileInfo.(LayerInfo); ok {
+		return fi.Layer()
+	}
+	return ""
+}
+
+// path returns the path of rel in the layer.
+func (l *Layer) path(rel string) string {
+	return filepath.Join(l.Root, rel)
+}
+
+// exists reports whether rel exists in the layer, and if it's a directory.
+func (l *Layer) exists(rel string) (ok, dir bool) {
+	fi, err := l.Fs.Stat(l.path(rel))
+	if err != nil {
+		return false, false
+	}
+	return true, fi.IsDir()
+}
+
+// hides reports whether the layer hides rel in the lower layers: rel or
+// one of its parents is whited out or is not a directory in the layer, or
+// one of its parents is opaque.
+func (l *Layer) hides(rel string) bool {
+	if rel == "." {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	dir := "."
+	for i, elem := range elems {
+		if ok, _ := l.exists(filepath.Join(dir, WhiteoutPrefix+elem)); ok {
+			return true
+		}
+		if i == len(elems)-1 {
+			break
+		}
+		dir = filepath.Join(dir, elem)
+		ok, isDir := l.exists(dir)
+		if !ok {
+			return false
+		}
+		if !isDir {
+			return true
+		}
+		if ok, _ := l.exists(filepath.Join(dir, OpaqueMarker)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the index of the uppermost layer that has rel, and its
+// FileInfo in the layer. Paths out of the root are not in any layer.
+func (fs *OverlayFs) find(rel string) (int, os.FileInfo, error) {
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return 0, nil, os.ErrNotExist
+	}
+	for i := len(fs.Layers) - 1; i >= 0; i-- {
+		l := &fs.Layers[i]
+		if fi, err := l.Fs.Stat(l.path(rel)); err == nil {
+			return i, fi, nil
+		}
+		if l.hides(rel) {
+			break
+		}
+	}
+	return 0, nil, os.ErrNotExist
+}
+
+// lookup returns the path of the named file relative to the root, the
+// index of the uppermost layer that has it, and its FileInfo in the layer.
+func (fs *OverlayFs) lookup(op, name string) (string, int, os.FileInfo, error) {
+	rel, err := filepath.Rel(fs.Root, name)
+	if err != nil {
+		return "", 0, nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	i, fi, err := fs.find(rel)
+	if err != nil {
+		return "", 0, nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return rel, i, fi, nil
+}
+
+// Stat returns the FileInfo of the named file in the uppermost layer that
+// has it.
+func (fs *OverlayFs) Stat(name string) (os.FileInfo, error) {
+	_, i, fi, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &layerInfo{FileInfo: fi, name: filepath.Base(name), layer: fs.Layers[i].Name}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory, in all
+// the layers it's not hidden in. The names of the upper layers come first.
+func (fs *OverlayFs) ReadDir(name string) ([]string, error) {
+	rel, top, fi, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	var names []string
+	// The names that were listed or whited out by the upper layers.
+	seen := make(map[string]bool)
+	for i := top; i >= 0; i-- {
+		l := &fs.Layers[i]
+		ok, isDir := l.exists(rel)
+		if ok && !isDir {
+			break
+		}
+		if ok {
+			entries, err := l.Fs.ReadDir(l.path(rel))
+			if err != nil {
+				return nil, err
+			}
+			var opaque bool
+			var whiteouts []string
+			for _, n := range entries {
+				switch {
+				case n == OpaqueMarker:
+					opaque = true
+				case strings.HasPrefix(n, WhiteoutPrefix):
+					whiteouts = append(whiteouts, strings.TrimPrefix(n, WhiteoutPrefix))
+				case !seen[n]:
+					seen[n] = true
+					names = append(names, n)
+				}
+			}
+			for _, n := range whiteouts {
+				seen[n] = true
+			}
+			if opaque {
+				break
+			}
+		}
+		if l.hides(rel) {
+			break
+		}
+	}
+	return names, nil
+}
+
+// Open opens the content of the named file, if the file system of its layer
+// is an Opener.
+func (fs *OverlayFs) Open(name string) (io.ReadCloser, error) {
+	rel, i, _, err := fs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	l := &fs.Layers[i]
+	o, ok := l.Fs.(Opener)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("layer " + l.Name + " can't be read")}
+	}
+	return o.Open(l.path(rel))
+}
+
+// Readlink returns the target of the named symbolic link, and the path it
+// resolves to in the merged directory. Absolute targets are resolved as is.
+func (fs *OverlayFs) Readlink(name string) (target, resolved string, err error) {
+	rel, i, fi, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", "", err
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	target, p, err := fs.Layers[i].readlink(rel)
+	if err != nil {
+		return "", "", err
+	}
+	if resolved, err = fs.eval(p); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, resolved, nil
+}
+
+// readlink returns the target of the link rel in the layer, and the path
+// it points to relative to the root, or absolute if it's out of the layer.
+// The links of a Readlinker are resolved by it, e.g: the hard links of an
+// archive are relative to its root.
+func (l *Layer) readlink(rel string) (target, p string, err error) {
+	if fs, ok := l.Fs.(Readlinker); ok {
+		target, resolved, err := fs.Readlink(l.path(rel))
+		if err != nil {
+			return "", "", err
+		}
+		if p, err = filepath.Rel(l.Root, resolved); err != nil {
+			p = resolved
+		}
+		return target, p, nil
+	}
+	if target, err = os.Readlink(l.path(rel)); err != nil {
+		return "", "", err
+	}
+	if p = target; !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(rel), p)
+	}
+	return target, p, nil
+}
+
+// eval returns the path of p after the evaluation of its symbolic links,
+// like filepath.EvalSymlinks. Missing files are resolved as is, and so are
+// absolute paths, that are out of the layers.
+func (fs *OverlayFs) eval(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return p, nil
+	}
+	r := vpath.Resolver{Readlink: func(p string) (string, bool, error) {
+		if path.IsAbs(p) {
+			return "", false, nil
+		}
+		rel := filepath.FromSlash(p)
+		i, fi, err := fs.find(rel)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		// The target is relative to the root, or absolute.
+		_, target, err := fs.Layers[i].readlink(rel)
+		if err == nil && !filepath.IsAbs(target) {
+			target, err = filepath.Rel(filepath.Dir(rel), target)
+		}
+		return filepath.ToSlash(target), true, err
+	}}
+	resolved, err := r.Eval(filepath.ToSlash(p))
+	if err != nil || path.IsAbs(resolved) {
+		return filepath.FromSlash(resolved), err
+	}
+	return filepath.Join(fs.Root, filepath.FromSlash(resolved)), nil
+}
+
+// Close closes the file systems of the layers that are io.Closers.
+func (fs *OverlayFs) Close() error {
+	var err error
+	for _, l := range fs.Layers {
+		if c, ok := l.Fs.(io.Closer); ok {
+			if e := c.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// layerInfo is the FileInfo of an OverlayFs file.
+type layerInfo struct {
+	os.FileInfo
+	name  string
+	layer string
+}
+
+func (fi *layerInfo) Name() string  { return fi.name }
+func (fi *layerInfo) Layer() string { return fi.layer }
@@ -0,0 +1,107 @@
+package tree
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// newOverlayFs returns the OverlayFs of the tests, with the layers:
+//
+//	defaults: a, b, c/x, c/y, d/e, f -> c/x
+//	site:     a, .wh.b, c/.wh..wh..opq, c/z, d (file)
+//	user:     g, h -> f
+func newOverlayFs(t *testing.T) *OverlayFs {
+	defaults, site, user := NewMemFs(), NewMemFs(), NewMemFs()
+	for _, err := range []error{
+		defaults.WriteFile("defaults/a", []byte("defaults"), 0644),
+		defaults.WriteFile("defaults/b", []byte("b"), 0644),
+		defaults.WriteFile("defaults/c/x", []byte("x"), 0644),
+		defaults.WriteFile("defaults/c/y", []byte("y"), 0644),
+		defaults.WriteFile("defaults/d/e", []byte("e"), 0644),
+		defaults.Symlink("c/x", "defaults/f"),
+		site.WriteFile("site/a", []byte("site"), 0644),
+		site.WriteFile("site/"+WhiteoutPrefix+"b", nil, 0644),
+		site.WriteFile("site/c/"+OpaqueMarker, nil, 0644),
+		site.WriteFile("site/c/z", []byte("z"), 0644),
+		site.WriteFile("site/d", nil, 0644),
+		user.WriteFile("user/g", []byte("g"), 0644),
+		user.Symlink("f", "user/h"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &OverlayFs{Root: "etc", Layers: []Layer{
+		{Name: "defaults", Fs: defaults, Root: "defaults"},
+		{Name: "site", Fs: site, Root: "site"},
+		{Name: "user", Fs: user, Root: "user"},
+	}}
+}
+
+var overlayTests = []treeTest{
+	{"basic", &Options{}, `etc
+├── a
+├── c
+│   └── z
+├── d
+├── f -> c/x
+├── g
+└── h -> f
+`, 1, 6},
+	{"show-layer", &Options{ShowLayer: true, ByteSize: true}, `[          6]  etc [layer: user]
+├── [          4]  a [layer: site]
+├── [          1]  c [layer: site]
+│   └── [          1]  z [layer: site]
+├── [          0]  d [layer: site]
+├── [          0]  f -> c/x [layer: defaults]
+├── [          1]  g [layer: user]
+└── [          0]  h -> f [layer: user]
+`, 1, 6},
+}
+
+func TestOverlayFs(t *testing.T) {
+	for _, test := range overlayTests {
+		test.opts.Fs = newOverlayFs(t)
+		test.opts.OutFile = out
+		inf := New("etc")
+		d, f := inf.Visit(test.opts)
+		if d != test.dirs {
+			t.Errorf("wrong dir count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, d, test.dirs)
+		}
+		if f != test.files {
+			t.Errorf("wrong file count for test %q:\ngot:\n%d\nexpected:\n%d", test.name, f, test.files)
+		}
+		inf.Print(test.opts)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestOverlayFsLookup(t *testing.T) {
+	fs := newOverlayFs(t)
+	for _, name := range []string{"etc/b", "etc/c/x", "etc/d/e", "etc/../defaults/a"} {
+		if _, err := fs.Stat(name); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected not exist error, got: %v", name, err)
+		}
+	}
+	// Links are resolved in the merged directory.
+	target, resolved, err := fs.Readlink("etc/h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "f" || resolved != "etc/c/x" {
+		t.Errorf("got %q, %q, expected: %q, %q", target, resolved, "f", "etc/c/x")
+	}
+	rc, err := fs.Open("etc/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "site" {
+		t.Errorf("got: %q, expected: %q", b, "site")
+	}
+}
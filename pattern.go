@@ -0,0 +1,226 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// glob is an alternative of a wildcard pattern, or a regular expression.
+type glob struct {
+	re *regexp.Regexp
+	// path is true if the pattern has a slash or "**", and is matched
+	// against the path relative to the root, instead of the name.
+	path bool
+	// dirOnly is true if the pattern ends with a slash.
+	dirOnly bool
+	// regex is true if re is a regular expression of --regex, that is
+	// searched in the name, or in the full path.
+	regex bool
+}
+
+// compileGlobs compiles the wildcard pattern, in the syntax of GNU tree:
+// "*" matches any characters but slashes, "**" matches any characters, "?"
+// matches a single character, "[...]" and "[^...]" match a character of a
+// class, and "|" separates alternative patterns.
+func compileGlobs(pattern string, ignoreCase bool) ([]*glob, error) {
+	var globs []*glob
+	for _, alt := range strings.Split(pattern, "|") {
+		g := &glob{dirOnly: strings.HasSuffix(alt, "/")}
+		alt = strings.TrimRight(alt, "/")
+		g.path = strings.Contains(alt, "/") || strings.Contains(alt, "**")
+		expr, err := wildcard(alt)
+		if err != nil {
+			return nil, err
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		if g.re, err = regexp.Compile(expr); err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+// wildcard returns the regular expression of a wildcard pattern.
+func wildcard(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if !strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString("[^/]*")
+				break
+			}
+			i++
+			// "/**/" also matches a single slash.
+			if strings.HasPrefix(pattern[i+1:], "/") && (i == 1 || pattern[i-2] == '/') {
+				b.WriteString("(?:.*/)?")
+				i++
+			} else {
+				b.WriteString(".*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, n := ignoreClass(pattern[i:])
+			if n == 0 {
+				return "", filepath.ErrBadPattern
+			}
+			b.WriteString(class)
+			i += n - 1
+		case '\\':
+			if i+1 == len(pattern) {
+				return "", filepath.ErrBadPattern
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+// compileRegex compiles the pattern as a regular expression.
+func compileRegex(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// matcher is a compiled set of patterns, that matches a node if any of
+// them does.
+type matcher []*glob
+
+// compileMatcher compiles the patterns, as wildcards or as regular
+// expressions. Empty patterns are skipped.
+func compileMatcher(patterns []string, regex, ignoreCase bool) (m matcher, err error) {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		var globs []*glob
+		if regex {
+			// Regular expressions with a "*" are matched against the path.
+			g := &glob{regex: true, path: strings.Contains(pattern, "*")}
+			g.re, err = compileRegex(pattern, ignoreCase)
+			globs = []*glob{g}
+		} else {
+			globs, err = compileGlobs(pattern, ignoreCase)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		m = append(m, globs...)
+	}
+	return
+}
+
+// patterns are the compiled include and exclude patterns, and the key of
+// the options they were compiled from.
+type patterns struct {
+	key     string
+	include matcher
+	exclude matcher
+}
+
+// patternKey returns the key of the pattern options, that is changed if
+// one of them is changed.
+func (opts *Options) patternKey() string {
+	return fmt.Sprintf("%t %t %q %q %q %q", opts.Regex, opts.IgnoreCase,
+		opts.Pattern, opts.Patterns, opts.IPattern, opts.IPatterns)
+}
+
+// Compile compiles the patterns of the options, and returns an error if
+// one of them is invalid. Visit uses the compiled patterns as long as the
+// pattern options are not changed. Otherwise, or if Compile was not called,
+// each Visit compiles the patterns for itself.
+func (opts *Options) Compile() error {
+	p, err := opts.compile()
+	if err != nil {
+		return err
+	}
+	opts.patterns = p
+	return nil
+}
+
+// compile compiles the patterns of the options.
+func (opts *Options) compile() (p *patterns, err error) {
+	p = &patterns{key: opts.patternKey()}
+	include := append([]string{opts.Pattern}, opts.Patterns...)
+	if p.include, err = compileMatcher(include, opts.Regex, opts.IgnoreCase); err != nil {
+		return nil, err
+	}
+	exclude := append([]string{opts.IPattern}, opts.IPatterns...)
+	if p.exclude, err = compileMatcher(exclude, opts.Regex, opts.IgnoreCase); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compiled returns the patterns compiled by Compile, or compiles them if
+// they were changed since.
+func (opts *Options) compiled() (*patterns, error) {
+	if p := opts.patterns; p != nil && p.key == opts.patternKey() {
+		return p, nil
+	}
+	return opts.compile()
+}
+
+// match reports whether the node matches one of the patterns. Directories
+// match only if MatchDirs is set, or if the wildcard pattern ends with a
+// slash.
+func (m matcher) match(node *Node, opts *Options) bool {
+	var rel string
+	for _, g := range m {
+		if g.dirOnly && !node.IsDir() || node.IsDir() && !g.dirOnly && !opts.MatchDirs {
+			continue
+		}
+		search := node.Name()
+		switch {
+		case g.regex && g.path:
+			search = node.path
+		case g.path:
+			if rel == "" {
+				rel = node.rel()
+			}
+			search = rel
+		}
+		if g.regex && g.re.FindString(search) != "" || !g.regex && g.re.MatchString(search) {
+			return true
+		}
+	}
+	return false
+}
+
+// rel returns the slash-separated path of the node, relative to the root.
+func (node *Node) rel() string {
+	elems := strings.Split(filepath.ToSlash(node.path), "/")
+	if node.depth < len(elems) {
+		elems = elems[len(elems)-node.depth:]
+	}
+	return strings.Join(elems, "/")
+}
+
+// ReadPatterns reads a file of patterns, e.g: of --exclude-from, that has a
+// pattern per line. Empty lines, and lines that start with "#" are skipped.
+func ReadPatterns(r io.Reader) (patterns []string, err error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, sc.Err()
+}
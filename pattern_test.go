@@ -0,0 +1,117 @@
+package tree
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWildcard(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matched bool
+	}{
+		{"*.go", "a.go", false, true},
+		{"*.go", "src/a.go", false, true},
+		{"*.go", "a.go.orig", false, false},
+		{"*.go|*.mod", "go.mod", false, true},
+		{"a?c", "abc", false, true},
+		{"a?c", "ac", false, false},
+		{"[ab]*", "b.txt", false, true},
+		{"[^ab]*", "b.txt", false, false},
+		{"[!ab]*", "c.txt", false, true},
+		{"[a-c]", "d", false, false},
+		{"\\*", "*", false, true},
+		{"a.(b)", "a.(b)", false, true},
+		{"src/*", "src/a.go", false, true},
+		{"src/*", "src/a/b.go", false, false},
+		{"src/**", "src/a/b.go", false, true},
+		{"src/**/*.go", "src/a.go", false, true},
+		{"src/**/*.go", "src/a/b/c.go", false, true},
+		{"**.go", "src/a/b.go", false, true},
+		{"**/test", "test", true, false},
+		{"**/test", "a/test", false, true},
+		{"vendor/", "a/vendor", true, true},
+		{"vendor/", "vendor", false, false},
+		{"vendor", "vendor", true, false},
+	}
+	for _, test := range tests {
+		fs := NewMemFs()
+		path := filepath.Join("root", test.path)
+		if test.isDir {
+			fs.Mkdir(path, 0755)
+		} else {
+			fs.WriteFile(path, nil, 0644)
+		}
+		fi, _ := fs.Stat(path)
+		node := &Node{FileInfo: fi, path: path, depth: strings.Count(test.path, "/") + 1}
+		m, err := compileMatcher([]string{test.pattern}, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if matched := m.match(node, &Options{}); matched != test.matched {
+			t.Errorf("%q matching %q: got %v, expected: %v", test.pattern, test.path, matched, test.matched)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		opts *Options
+		err  string
+	}{
+		{&Options{Pattern: "*.go|[a-z]*"}, ""},
+		{&Options{Pattern: "(a"}, ""},
+		{&Options{IPattern: "[a-"}, `invalid pattern "[a-": syntax error in pattern`},
+		{&Options{Pattern: "a\\"}, `invalid pattern "a\\": syntax error in pattern`},
+		{&Options{Pattern: "*.go", Regex: true}, "invalid pattern \"*.go\": error parsing regexp: missing argument to repetition operator: `*`"},
+		{&Options{Patterns: []string{"*.go", "*.mod"}, IPatterns: []string{"*_test.go", "[z"}}, `invalid pattern "[z": syntax error in pattern`},
+	}
+	for _, test := range tests {
+		var msg string
+		if err := test.opts.Compile(); err != nil {
+			msg = err.Error()
+		}
+		if msg != test.err {
+			t.Errorf("%q: got error %q, expected: %q", test.opts.Pattern+test.opts.IPattern, msg, test.err)
+		}
+	}
+}
+
+// The patterns compiled by Compile are used by Visit until they are
+// changed, and Visit doesn't change the options.
+func TestCompiledPatterns(t *testing.T) {
+	fs := NewMemFs()
+	fs.WriteFile("a/x.go", nil, 0644)
+	fs.WriteFile("a/y.md", nil, 0644)
+	opts := &Options{Fs: fs, Pattern: "*.go"}
+	if _, f := New("a").Visit(opts); f != 1 || opts.patterns != nil {
+		t.Fatalf("expected the patterns to be compiled by Visit for itself, got %d files", f)
+	}
+	if err := opts.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	inf := New("a")
+	if _, f := inf.Visit(opts); f != 1 || inf.patterns != opts.patterns {
+		t.Errorf("expected the compiled patterns to be used")
+	}
+	opts.Pattern = "*.md"
+	inf = New("a")
+	if _, f := inf.Visit(opts); f != 1 || inf.patterns == opts.patterns || inf.nodes[0].Name() != "y.md" {
+		t.Errorf("expected the changed patterns to be compiled again")
+	}
+}
+
+func TestReadPatterns(t *testing.T) {
+	r := strings.NewReader("# build outputs\n*.o\r\n\n  bin/  \nvendor/**|*.tmp\n")
+	patterns, err := ReadPatterns(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"*.o", "bin/", "vendor/**|*.tmp"}
+	if strings.Join(patterns, ",") != strings.Join(expected, ",") {
+		t.Errorf("got: %q, expected: %q", patterns, expected)
+	}
+}
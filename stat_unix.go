@@ -18,3 +18,11 @@ func getStat(fi os.FileInfo) (ok bool, inode, device, uid, gid uint64) {
 	}
 	return true, uint64(stat.Ino), uint64(stat.Dev), uint64(stat.Uid), uint64(stat.Gid)
 }
+
+func getUsage(fi os.FileInfo) (ok bool, blocks, nlink uint64) {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false, 0, 0
+	}
+	return true, uint64(stat.Blocks), uint64(stat.Nlink)
+}
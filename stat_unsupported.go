@@ -7,3 +7,7 @@ import "os"
 func getStat(fi os.FileInfo) (ok bool, inode, device, uid, gid uint64) {
 	return false, 0, 0, 0, 0
 }
+
+func getUsage(fi os.FileInfo) (ok bool, blocks, nlink uint64) {
+	return false, 0, 0
+}
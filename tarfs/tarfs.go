@@ -0,0 +1,207 @@
+// Package tarfs implements tree.Fs over the entries of a tar archive.
+package tarfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// FS is a tree.Fs of a tar archive, that is indexed when it's created.
+// Parent directories that are not in the archive are implied, symbolic
+// links and hard links are listed as links, and the Sys method of the
+// FileInfo returns the *Header of the entry.
+type FS struct {
+	// Root is the path of the archive root, e.g: "release.tar.gz".
+	// It's "." for archives that are not opened with Open.
+	Root  string
+	index *vpath.Index
+}
+
+// Open indexes the tar archive at the given path, that can be compressed
+// with gzip or bzip2. The path is the root of the FS.
+func Open(path string) (*FS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fs, err := New(f)
+	if err != nil {
+		return nil, err
+	}
+	fs.Root = path
+	return fs, nil
+}
+
+// New indexes the tar archive read from r, that can be compressed with
+// gzip or bzip2.
+func New(r io.Reader) (*FS, error) {
+	r, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	fs := &FS{Root: ".", index: vpath.NewIndex(&tar.Header{Typeflag: tar.TypeDir, Name: "./", Mode: 0755})}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fs.add(hdr)
+	}
+}
+
+// decompress returns a reader of the decompressed data of r, if it starts
+// with the magic number of gzip or bzip2.
+func decompress(r *bufio.Reader) (io.Reader, error) {
+	magic, _ := r.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(r)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return bzip2.NewReader(r), nil
+	}
+	return r, nil
+}
+
+// add adds the header to the index. Entries that appear more than once
+// are replaced, as they are when the archive is extracted.
+func (fs *FS) add(hdr *tar.Header) {
+	if hdr.Typeflag == tar.TypeXGlobalHeader {
+		return
+	}
+	name := vpath.Clean(hdr.Name)
+	if name == "." && hdr.Typeflag != tar.TypeDir {
+		return
+	}
+	fs.index.Add(name, hdr, func(dir string) interface{} {
+		return &tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: hdr.ModTime}
+	})
+}
+
+// lookup returns the header of the entry at the given path.
+func (fs *FS) lookup(op, p string) (string, *tar.Header, error) {
+	rel, v, err := fs.index.Lookup(op, fs.Root, p)
+	if err != nil {
+		return "", nil, err
+	}
+	return rel, v.(*tar.Header), nil
+}
+
+// Stat returns the FileInfo of the entry with the given name.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	rel, hdr, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		name = fs.Root
+	}
+	return &fileInfo{hdr.FileInfo(), filepath.Base(name)}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	rel, _, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.index.Names(rel), nil
+}
+
+// Readlink returns the target of the named link, and the path it resolves
+// to. Absolute targets are resolved from the archive root, and
+// hard link targets are always relative to the archive root.
+func (fs *FS) Readlink(name string) (target, resolved string, err error) {
+	rel, hdr, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", "", err
+	}
+	target = hdr.Linkname
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		resolved = vpath.Clean(target)
+	case tar.TypeSymlink:
+		p := target
+		if !strings.HasPrefix(p, "/") {
+			p = path.Dir(rel) + "/" + p
+		}
+		if resolved, err = fs.eval(p); err != nil {
+			return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+		}
+	default:
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return target, filepath.Join(fs.Root, filepath.FromSlash(resolved)), nil
+}
+
+// eval returns the archive path of p after the evaluation of its symbolic
+// links. Missing entries are resolved as is.
+func (fs *FS) eval(p string) (string, error) {
+	r := vpath.Resolver{Chroot: true, Readlink: func(p string) (string, bool, error) {
+		v, ok := fs.index.Get(p)
+		if !ok || v.(*tar.Header).Typeflag != tar.TypeSymlink {
+			return "", false, nil
+		}
+		return v.(*tar.Header).Linkname, true, nil
+	}}
+	return r.Eval(p)
+}
+
+// fileInfo is the FileInfo of an entry. Its name is the name in the tree,
+// and hard links have the os.ModeSymlink bit.
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *fileInfo) Name() string { return fi.name }
+
+func (fi *fileInfo) Mode() os.FileMode {
+	mode := fi.FileInfo.Mode()
+	if fi.FileInfo.Sys().(*tar.Header).Typeflag == tar.TypeLink {
+		mode |= os.ModeSymlink
+	}
+	return mode
+}
+
+func (fi *fileInfo) Sys() interface{} { return &Header{fi.FileInfo.Sys().(*tar.Header)} }
+
+// Header is the tar header of an entry, that is returned by the Sys method
+// of its FileInfo. It implements tree.SysInfo.
+type Header struct {
+	*tar.Header
+}
+
+// Owner returns the owner ids of the entry, and their names, that default
+// to the ids.
+func (h *Header) Owner() (uid, gid uint64, user, group string) {
+	uid, gid = uint64(h.Uid), uint64(h.Gid)
+	user, group = h.Uname, h.Gname
+	if user == "" {
+		user = strconv.FormatUint(uid, 10)
+	}
+	if group == "" {
+		group = strconv.FormatUint(gid, 10)
+	}
+	return
+}
+
+// Times returns the access and status change times of the entry, that are
+// only set in the PAX and GNU formats.
+func (h *Header) Times() (atime, ctime time.Time) { return h.AccessTime, h.ChangeTime }
@@ -0,0 +1,157 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+var mtime = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+// archive returns a tar archive with the given headers, and their names
+// as the content of the regular files.
+func archive(t *testing.T, hdrs []*tar.Header) *bytes.Buffer {
+	b := new(bytes.Buffer)
+	w := tar.NewWriter(b)
+	for _, hdr := range hdrs {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(hdr.Name))
+		}
+		hdr.ModTime = mtime
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			io.WriteString(w, hdr.Name)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var headers = []*tar.Header{
+	{Typeflag: tar.TypeDir, Name: "release/", Mode: 0755, Uname: "root", Gname: "wheel"},
+	{Typeflag: tar.TypeReg, Name: "release/bin/tree", Mode: 0755, Uid: 1000, Gid: 1000},
+	{Typeflag: tar.TypeReg, Name: "release/doc/README.md", Mode: 0644, Uname: "alice", Gname: "staff"},
+	{Typeflag: tar.TypeSymlink, Name: "release/README.md", Linkname: "doc/README.md", Mode: 0777},
+	{Typeflag: tar.TypeSymlink, Name: "release/adoc", Linkname: "/release/doc", Mode: 0777},
+	{Typeflag: tar.TypeSymlink, Name: "release/bin/doc", Linkname: "../adoc", Mode: 0777},
+	{Typeflag: tar.TypeLink, Name: "release/bin/t", Linkname: "release/bin/tree"},
+	{Typeflag: tar.TypeSymlink, Name: "release/missing", Linkname: "nowhere", Mode: 0777},
+	{Typeflag: tar.TypeReg, Name: "./release/bin/../LICENSE", Mode: 0600},
+}
+
+var tests = []struct {
+	name     string
+	root     string
+	opts     *tree.Options
+	expected string
+}{
+	{"basic", "a.tar", &tree.Options{}, `a.tar
+└── release
+    ├── LICENSE
+    ├── README.md -> doc/README.md
+    ├── adoc -> /release/doc
+    ├── bin
+    │   ├── doc -> ../adoc
+    │   ├── t -> release/bin/tree
+    │   └── tree
+    ├── doc
+    │   └── README.md
+    └── missing -> nowhere
+`},
+	{"follow", "a.tar/release/bin", &tree.Options{FollowLink: true}, `a.tar/release/bin
+├── doc -> ../adoc
+│   └── README.md
+├── t -> release/bin/tree
+└── tree
+`},
+	{"color", "a.tar/release", &tree.Options{Colorize: true, Pattern: "adoc|missing", Prune: true},
+		"\x1b[1;34ma.tar/release\x1b[0m\n" +
+			"├── \x1b[1;36madoc\x1b[0m -> \x1b[1;34m/release/doc\x1b[0m\n" +
+			"└── \x1b[40;1;31mmissing\x1b[0m -> nowhere\n"},
+	{"props", "a.tar", &tree.Options{FileMode: true, ShowUid: true, ShowGid: true, ByteSize: true, LastMod: true, Pattern: "tree|LICENSE", Prune: true}, `[         40]  a.tar
+└── [         40]  release
+    ├── [-rw------- 0        0             24 Jan 02  2006]  LICENSE
+    └── [         16]  bin
+        └── [-rwxr-xr-x 1000     1000          16 Jan 02  2006]  tree
+`},
+}
+
+func TestFS(t *testing.T) {
+	b := archive(t, headers)
+	gz := new(bytes.Buffer)
+	w := gzip.NewWriter(gz)
+	w.Write(b.Bytes())
+	w.Close()
+	for _, r := range []io.Reader{bytes.NewReader(b.Bytes()), gz} {
+		fs, err := New(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs.Root = "a.tar"
+		for _, test := range tests {
+			out := new(bytes.Buffer)
+			test.opts.Fs = fs
+			test.opts.OutFile = out
+			inf := tree.New(test.root)
+			inf.Visit(test.opts)
+			inf.Print(test.opts)
+			if actual := out.String(); actual != test.expected {
+				t.Errorf("%s:\nactual\n%s\n != expect\n%s\n", test.name, actual, test.expected)
+			}
+		}
+	}
+}
+
+func TestReadlink(t *testing.T) {
+	fs, err := New(archive(t, headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct{ name, target, resolved string }{
+		{"release/README.md", "doc/README.md", "release/doc/README.md"},
+		{"release/adoc", "/release/doc", "release/doc"},
+		{"release/bin/t", "release/bin/tree", "release/bin/tree"},
+		{"release/missing", "nowhere", "release/nowhere"},
+	} {
+		target, resolved, err := fs.Readlink(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != test.target || resolved != filepath.FromSlash(test.resolved) {
+			t.Errorf("%s: got %q, %q expected %q, %q", test.name, target, resolved, test.target, test.resolved)
+		}
+	}
+	if _, _, err := fs.Readlink("release/LICENSE"); err == nil {
+		t.Error("expected an error for a regular file")
+	}
+	fi, err := fs.Stat("release/doc/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr, ok := fi.Sys().(*Header); !ok || hdr.Uname != "alice" {
+		t.Errorf("expected the tar header in Sys, got %#v", fi.Sys())
+	}
+	if st, ok := fi.Sys().(tree.SysInfo); !ok {
+		t.Errorf("expected a tree.SysInfo in Sys, got %#v", fi.Sys())
+	} else if uid, _, user, group := st.Owner(); uid != 0 || user != "alice" || group != "staff" {
+		t.Errorf("unexpected owner %d %s %s", uid, user, group)
+	}
+	if _, err := fs.Stat("../a"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	if names, _ := fs.ReadDir("."); strings.Join(names, ",") != "release" {
+		t.Errorf("unexpected root entries %v", names)
+	}
+}
@@ -0,0 +1,94 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+// xmlPrinter prints the tree as XML, using the same layout as `tree -X`.
+// The output is described by the schema in tree.xsd:
+//
+//	<?xml version="1.0" encoding="UTF-8"?>
+//	<tree>
+//	  <directory name="root">
+//	    <file name="a"></file>
+//	  </directory>
+//	  <report>
+//	    <directories>0</directories>
+//	    <files>1</files>
+//	  </report>
+//	</tree>
+type xmlPrinter struct{}
+
+func (xmlPrinter) begin(opts *Options) {
+	fmt.Fprint(opts.OutFile, xml.Header)
+	fmt.Fprintln(opts.OutFile, "<tree>")
+}
+
+func (xmlPrinter) print(node *Node, i int, opts *Options) { node.printXML("  ", opts) }
+
+func (xmlPrinter) end(dirs, files int, opts *Options) {
+	w := opts.OutFile
+	if !opts.NoReport {
+		fmt.Fprintln(w, "  <report>")
+		fmt.Fprintf(w, "    <directories>%d</directories>\n", dirs)
+		if !opts.DirsOnly {
+			fmt.Fprintf(w, "    <files>%d</files>\n", files)
+		}
+		fmt.Fprintln(w, "  </report>")
+	}
+	fmt.Fprintln(w, "</tree>")
+}
+
+func (node *Node) printXML(indent string, opts *Options) {
+	w := opts.OutFile
+	name := xmlString(node.name(opts))
+	// The node could not be stat'ed, there's nothing to say about it but the error.
+	if node.FileInfo == nil {
+		fmt.Fprintf(w, "%s<error name=\"%s\">%s</error>\n", indent, name, xmlString(node.errMsg()))
+		return
+	}
+	kind := node.kind()
+	fmt.Fprintf(w, "%s<%s name=\"%s\"", indent, kind, name)
+	for _, attr := range node.xmlAttrs(opts) {
+		fmt.Fprintf(w, " %s", attr)
+	}
+	var recursive bool
+	if node.err == nil && node.Mode()&os.ModeSymlink != 0 {
+		var target string
+		target, _, recursive = node.link(opts)
+		fmt.Fprintf(w, " target=\"%s\"", xmlString(target))
+	}
+	fmt.Fprint(w, ">")
+	switch {
+	case node.err != nil:
+		fmt.Fprintf(w, "\n%s  <error>%s</error>\n%s", indent, xmlString(node.errMsg()), indent)
+	case recursive:
+		fmt.Fprintf(w, "\n%s  <error>recursive, not followed</error>\n%s", indent, indent)
+	case len(node.nodes) > 0:
+		fmt.Fprintln(w)
+		for _, nnode := range node.nodes {
+			nnode.printXML(indent+"  ", opts)
+		}
+		fmt.Fprint(w, indent)
+	}
+	fmt.Fprintf(w, "</%s>\n", kind)
+}
+
+// xmlAttrs returns the metadata attributes enabled by the options, encoded
+// as `key="value"` pairs.
+func (node *Node) xmlAttrs(opts *Options) (attrs []string) {
+	for _, f := range node.fields(opts) {
+		attrs = append(attrs, fmt.Sprintf(`%s="%s"`, f.key, xmlString(fmt.Sprint(f.value))))
+	}
+	return
+}
+
+// xmlString escapes s to be used as XML character data or attribute value.
+func xmlString(s string) string {
+	b := new(bytes.Buffer)
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package tree
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+var xmlTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, XML: true}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root">
+    <file name="a &amp; &lt;b&gt;"></file>
+    <directory name="c">
+      <file name="d &#34;quoted&#34;"></file>
+    </directory>
+    <directory name="e"></directory>
+    <error name="bad">stat failed</error>
+  </directory>
+  <report>
+    <directories>2</directories>
+    <files>2</files>
+  </report>
+</tree>
+`, 2, 2},
+	{"dirs + noreport", &Options{Fs: fs, OutFile: out, XML: true, DirsOnly: true, NoReport: true}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root">
+    <directory name="c"></directory>
+    <directory name="e"></directory>
+    <error name="bad">stat failed</error>
+  </directory>
+</tree>
+`, 2, 0},
+	{"metadata", &Options{Fs: fs, OutFile: out, XML: true, FileMode: true, ByteSize: true, Inodes: true, Device: true, DeepLevel: 1}, `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="root" inode="1" dev="9" mode="0755" prot="drwxr-xr-x" size="100">
+    <file name="a &amp; &lt;b&gt;" inode="2" dev="9" mode="0644" prot="-rw-r--r--" size="100"></file>
+    <directory name="c" inode="3" dev="9" mode="0700" prot="drwx------"></directory>
+    <directory name="e" inode="4" dev="9" mode="0755" prot="drwxr-xr-x"></directory>
+    <error name="bad">stat failed</error>
+  </directory>
+  <report>
+    <directories>2</directories>
+    <files>1</files>
+  </report>
+</tree>
+`, 2, 1},
+}
+
+func TestXML(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir | 0755,
+		stat: &syscall.Stat_t{Ino: 1, Dev: 9},
+		files: []*file{
+			{name: "a & <b>", size: 100, mode: 0644, stat: &syscall.Stat_t{Ino: 2, Dev: 9}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:  "c",
+				mode:  os.ModeDir | 0700,
+				stat:  &syscall.Stat_t{Ino: 3, Dev: 9},
+				files: []*file{{name: `d "quoted"`, size: 50}},
+			},
+			{name: "e", mode: os.ModeDir | 0755, stat: &syscall.Stat_t{Ino: 4, Dev: 9}, files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range xmlTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		dec := xml.NewDecoder(strings.NewReader(out.str))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s: invalid XML output: %v", test.name, err)
+				break
+			}
+		}
+		out.clear()
+	}
+}
+
+// The output, with all the metadata, should be valid against tree.xsd.
+func TestXMLSchema(t *testing.T) {
+	if _, err := exec.LookPath("xmllint"); err != nil {
+		t.Skip("xmllint is not installed")
+	}
+	opts := &Options{
+		Fs:        newOverlayFs(t),
+		OutFile:   out,
+		XML:       true,
+		FileMode:  true,
+		ShowUid:   true,
+		ShowGid:   true,
+		ByteSize:  true,
+		LastMod:   true,
+		ShowLayer: true,
+	}
+	defer out.clear()
+	inf := New("etc")
+	d, f := inf.Visit(opts)
+	p := NewPrinter(opts)
+	p.Print(inf)
+	p.End(d, f)
+	if !strings.Contains(out.str, ` layer="site"`) {
+		t.Errorf("missing the layers in:\n%s", out.str)
+	}
+	cmd := exec.Command("xmllint", "--noout", "--schema", "tree.xsd", "-")
+	cmd.Stdin = strings.NewReader(out.str)
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("invalid XML output: %v\n%s\n%s", err, b, out.str)
+	}
+}
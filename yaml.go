@@ -0,0 +1,112 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// yamlPrinter prints the tree as a YAML sequence, with the same structure
+// and fields as the JSON output:
+//
+//	# tree --format=yaml root
+//	- type: directory
+//	  name: root
+//	  contents:
+//	    - type: file
+//	      name: a
+//	- type: report
+//	  directories: 0
+//	  files: 1
+type yamlPrinter struct{}
+
+func (yamlPrinter) begin(opts *Options) {}
+
+func (yamlPrinter) print(node *Node, i int, opts *Options) { node.printYAML("", opts) }
+
+func (yamlPrinter) end(dirs, files int, opts *Options) {
+	if opts.NoReport {
+		return
+	}
+	fmt.Fprintf(opts.OutFile, "- type: report\n  directories: %d\n", dirs)
+	if !opts.DirsOnly {
+		fmt.Fprintf(opts.OutFile, "  files: %d\n", files)
+	}
+}
+
+func (node *Node) printYAML(indent string, opts *Options) {
+	w := opts.OutFile
+	fmt.Fprintf(w, "%s- type: %s\n", indent, node.kind())
+	indent += "  "
+	fmt.Fprintf(w, "%sname: %s\n", indent, yamlString(node.name(opts)))
+	if node.FileInfo != nil {
+		for _, f := range node.fields(opts) {
+			if s, ok := f.value.(string); ok {
+				fmt.Fprintf(w, "%s%s: %s\n", indent, f.key, yamlString(s))
+			} else {
+				fmt.Fprintf(w, "%s%s: %d\n", indent, f.key, f.value)
+			}
+		}
+	}
+	if node.err != nil {
+		fmt.Fprintf(w, "%serror: %s\n", indent, yamlString(node.errMsg()))
+		return
+	}
+	if node.Mode()&os.ModeSymlink != 0 {
+		target, _, recursive := node.link(opts)
+		fmt.Fprintf(w, "%starget: %s\n", indent, yamlString(target))
+		if recursive {
+			fmt.Fprintf(w, "%serror: recursive, not followed\n", indent)
+		}
+	}
+	if !node.IsDir() && len(node.nodes) == 0 {
+		return
+	}
+	if len(node.nodes) == 0 {
+		fmt.Fprintf(w, "%scontents: []\n", indent)
+		return
+	}
+	fmt.Fprintf(w, "%scontents:\n", indent)
+	for _, nnode := range node.nodes {
+		nnode.printYAML(indent+"  ", opts)
+	}
+}
+
+// YAML 1.1 and 1.2 words that are not read as strings when left unquoted.
+var yamlWords = map[string]bool{
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true, "~": true,
+	".inf": true, "-.inf": true, "+.inf": true, ".nan": true,
+}
+
+// yamlString returns s as a YAML scalar. It's written as a plain scalar
+// if it's safe, or double-quoted if it could be read as anything but the
+// same string, e.g: "yes", "null", "007" or "a: b".
+func yamlString(s string) string {
+	if yamlPlain(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+func yamlPlain(s string) bool {
+	switch {
+	case s == "" || yamlWords[strings.ToLower(s)]:
+		return false
+	// Indicators, numbers, and leading or trailing spaces.
+	case strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`<=+.0123456789 \t", rune(s[0])):
+		return false
+	case strings.HasSuffix(s, ":") || strings.HasSuffix(s, " "):
+		return false
+	case strings.Contains(s, ": ") || strings.Contains(s, " #"):
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) || r == unicode.ReplacementChar {
+			return false
+		}
+	}
+	return true
+}
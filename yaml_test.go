@@ -0,0 +1,124 @@
+package tree
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+var yamlTests = []treeTest{
+	{"basic", &Options{Fs: fs, OutFile: out, YAML: true}, `- type: directory
+  name: root
+  contents:
+    - type: file
+      name: "Off"
+    - type: directory
+      name: c
+      contents:
+        - type: file
+          name: "a: b"
+    - type: directory
+      name: e
+      contents: []
+    - type: file
+      name: bad
+      error: stat failed
+- type: report
+  directories: 2
+  files: 2
+`, 2, 2},
+	{"metadata + noreport", &Options{Fs: fs, OutFile: out, YAML: true, FileMode: true, ByteSize: true, Inodes: true, DeepLevel: 1, NoReport: true}, `- type: directory
+  name: root
+  inode: 1
+  mode: "0755"
+  prot: drwxr-xr-x
+  size: 100
+  contents:
+    - type: file
+      name: "Off"
+      inode: 2
+      mode: "0644"
+      prot: "-rw-r--r--"
+      size: 100
+    - type: directory
+      name: c
+      inode: 3
+      mode: "0700"
+      prot: drwx------
+      contents: []
+    - type: directory
+      name: e
+      inode: 4
+      mode: "0755"
+      prot: drwxr-xr-x
+      contents: []
+    - type: file
+      name: bad
+      error: stat failed
+`, 2, 1},
+}
+
+func TestYAML(t *testing.T) {
+	root := &file{
+		name: "root",
+		mode: os.ModeDir | 0755,
+		stat: &syscall.Stat_t{Ino: 1},
+		files: []*file{
+			{name: "Off", size: 100, mode: 0644, stat: &syscall.Stat_t{Ino: 2}},
+			{name: "bad"}, // stat fails on this file
+			{
+				name:  "c",
+				mode:  os.ModeDir | 0700,
+				stat:  &syscall.Stat_t{Ino: 3},
+				files: []*file{{name: "a: b", size: 50}},
+			},
+			{name: "e", mode: os.ModeDir | 0755, stat: &syscall.Stat_t{Ino: 4}, files: []*file{}},
+		},
+	}
+	fs.clean().addFile(root.name, root)
+	for _, test := range yamlTests {
+		inf := New(root.name)
+		d, f := inf.Visit(test.opts)
+		p := NewPrinter(test.opts)
+		p.Print(inf)
+		p.End(d, f)
+		if !out.equal(test.expected) {
+			t.Errorf("%s:\ngot:\n%+v\nexpected:\n%+v", test.name, out.str, test.expected)
+		}
+		out.clear()
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected string
+	}{
+		{"main.go", "main.go"},
+		{"a b", "a b"},
+		{"a:b", "a:b"},
+		{"a#b", "a#b"},
+		{"", `""`},
+		{"yes", `"yes"`},
+		{"No", `"No"`},
+		{"null", `"null"`},
+		{"~", `"~"`},
+		{"007", `"007"`},
+		{"1.5", `"1.5"`},
+		{".inf", `".inf"`},
+		{"-x", `"-x"`},
+		{"a: b", `"a: b"`},
+		{"a #b", `"a #b"`},
+		{"x:", `"x:"`},
+		{" x", `" x"`},
+		{"x ", `"x "`},
+		{"[x]", `"[x]"`},
+		{`"x"`, `"\"x\""`},
+		{"a\nb", `"a\nb"`},
+		{"a\tb", `"a\tb"`},
+	} {
+		if actual := yamlString(test.s); actual != test.expected {
+			t.Errorf("yamlString(%q):\ngot:\n%s\nexpected:\n%s", test.s, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package zipfs implements tree.Fs over the entries of a zip archive, e.g:
+// zip, jar or wheel files.
+package zipfs
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/a8m/tree/internal/vpath"
+)
+
+// FS is a tree.Fs of a zip archive. The entries are read through an
+// io.ReaderAt, so the archive isn't loaded into memory. Parent directories
+// that are not in the archive are implied, the mode bits are taken from
+// the external attributes, and the Sys method of the FileInfo returns the
+// *Header of the entry, with its compressed and uncompressed sizes.
+type FS struct {
+	// Root is the path of the archive root, e.g: "app.jar".
+	// It's "." for archives that are not opened with Open.
+	Root   string
+	index  *vpath.Index
+	closer io.Closer
+}
+
+// Open opens the zip archive at the given path. The path is the root of
+// the FS, and the archive is closed with Close.
+func Open(path string) (*FS, error) {
+	rc, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	fs := newFS(&rc.Reader)
+	fs.Root, fs.closer = path, rc
+	return fs, nil
+}
+
+// New returns the FS of the zip archive read from r, with the given size.
+func New(r io.ReaderAt, size int64) (*FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newFS(zr), nil
+}
+
+func newFS(zr *zip.Reader) *FS {
+	fs := &FS{Root: ".", index: vpath.NewIndex(dir(".", time.Time{}))}
+	for _, zf := range zr.File {
+		fs.add(zf)
+	}
+	return fs
+}
+
+// Close closes the archive, if it was opened with Open.
+func (fs *FS) Close() error {
+	if fs.closer == nil {
+		return nil
+	}
+	return fs.closer.Close()
+}
+
+// add adds the entry to the index. Entries that appear more than once are
+// replaced, as they are when the archive is extracted.
+func (fs *FS) add(zf *zip.File) {
+	name := vpath.Clean(zf.Name)
+	if name == "." {
+		return
+	}
+	fs.index.Add(name, zf, func(name string) interface{} { return dir(name, zf.Modified) })
+}
+
+// dir returns the entry of an implied directory.
+func dir(name string, modTime time.Time) *zip.File {
+	fh := zip.FileHeader{Name: name + "/", Modified: modTime}
+	fh.SetMode(os.ModeDir | 0755)
+	return &zip.File{FileHeader: fh}
+}
+
+// lookup returns the entry at the given path.
+func (fs *FS) lookup(op, p string) (string, *zip.File, error) {
+	rel, v, err := fs.index.Lookup(op, fs.Root, p)
+	if err != nil {
+		return "", nil, err
+	}
+	return rel, v.(*zip.File), nil
+}
+
+// Stat returns the FileInfo of the entry with the given name.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	rel, zf, err := fs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		name = fs.Root
+	}
+	return &fileInfo{zf.FileInfo(), filepath.Base(name)}, nil
+}
+
+// ReadDir returns the names of the entries of the named directory.
+func (fs *FS) ReadDir(name string) ([]string, error) {
+	rel, _, err := fs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.index.Names(rel), nil
+}
+
+// Open opens the content of the named file.
+func (fs *FS) Open(name string) (io.ReadCloser, error) {
+	_, zf, err := fs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if zf.Mode().IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return zf.Open()
+}
+
+// Readlink returns the target of the named symbolic link, and the path it
+// resolves to. Absolute targets are resolved from the archive root.
+func (fs *FS) Readlink(name string) (target, resolved string, err error) {
+	rel, zf, err := fs.lookup("readlink", name)
+	if err != nil {
+		return "", "", err
+	}
+	if target, err = linkname(zf); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	p := target
+	if !strings.HasPrefix(p, "/") {
+		p = path.Dir(rel) + "/" + p
+	}
+	if resolved, err = fs.eval(p); err != nil {
+		return "", "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, filepath.Join(fs.Root, filepath.FromSlash(resolved)), nil
+}
+
+// linkname returns the target of a symbolic link, that is its content.
+func linkname(zf *zip.File) (string, error) {
+	if zf.Mode()&os.ModeSymlink == 0 {
+		return "", os.ErrInvalid
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+	return string(b), err
+}
+
+// eval returns the archive path of p after the evaluation of its symbolic
+// links. Missing entries are resolved as is.
+func (fs *FS) eval(p string) (string, error) {
+	r := vpath.Resolver{Chroot: true, Readlink: func(p string) (string, bool, error) {
+		v, ok := fs.index.Get(p)
+		if !ok || v.(*zip.File).Mode()&os.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		target, err := linkname(v.(*zip.File))
+		return target, true, err
+	}}
+	return r.Eval(p)
+}
+
+// fileInfo is the FileInfo of an entry. Its name is the name in the tree.
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi *fileInfo) Name() string { return fi.name }
+
+func (fi *fileInfo) Sys() interface{} { return &Header{fi.FileInfo.Sys().(*zip.FileHeader)} }
+
+// Header is the zip header of an entry, that is returned by the Sys method
+// of its FileInfo. It implements tree.PackedSizer, and the size of the
+// FileInfo is the uncompressed size.
+type Header struct {
+	*zip.FileHeader
+}
+
+// PackedSize returns the size of the entry in the archive, that is its
+// CompressedSize64.
+func (h *Header) PackedSize() int64 { return int64(h.CompressedSize64) }
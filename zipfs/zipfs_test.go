@@ -0,0 +1,161 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a8m/tree"
+)
+
+var mtime = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+
+var entries = []struct {
+	name    string
+	mode    os.FileMode
+	content string
+	method  uint16
+}{
+	{"app/", os.ModeDir | 0750, "", zip.Store},
+	{"app/bin/run", 0755, "#!/bin/sh\n", zip.Store},
+	{"app/lib/core.jar", 0644, strings.Repeat("a", 1000), zip.Deflate},
+	{"app/lib/latest.jar", os.ModeSymlink | 0777, "core.jar", zip.Store},
+	{"META-INF/MANIFEST.MF", 0600, "Manifest-Version: 1.0\n", zip.Store},
+}
+
+// archive returns a zip archive with the test entries.
+func archive(t *testing.T) *bytes.Reader {
+	b := new(bytes.Buffer)
+	w := zip.NewWriter(b)
+	for _, e := range entries {
+		fh := &zip.FileHeader{Name: e.name, Method: e.method, Modified: mtime}
+		fh.SetMode(e.mode)
+		f, err := w.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(f, e.content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b.Bytes())
+}
+
+var tests = []struct {
+	name     string
+	opts     *tree.Options
+	expected string
+}{
+	{"basic", &tree.Options{}, `app.jar
+├── META-INF
+│   └── MANIFEST.MF
+└── app
+    ├── bin
+    │   └── run
+    └── lib
+        ├── core.jar
+        └── latest.jar -> core.jar
+`},
+	{"mode", &tree.Options{FileMode: true, DeepLevel: 2}, `app.jar
+├── META-INF
+│   └── [-rw-------]  MANIFEST.MF
+└── app
+    ├── bin
+    └── lib
+`},
+	{"size", &tree.Options{ByteSize: true, Pattern: "*.jar|run", Prune: true}, `[       1018]  app.jar
+└── [       1018]  app
+    ├── [         10]  bin
+    │   └── [         10]  run
+    └── [       1008]  lib
+        ├── [       1000]  core.jar
+        └── [          8]  latest.jar -> core.jar
+`},
+	{"compressed", &tree.Options{ByteSize: true, CompressedSize: true, Pattern: "core*", Prune: true}, `[       1000]  app.jar
+└── [       1000]  app
+    └── [       1000]  lib
+        └── [       1000          11]  core.jar
+`},
+	{"compressed json", &tree.Options{JSON: true, CompressedSize: true, Pattern: "core*", Prune: true}, `  {"type":"directory","name":"app.jar","contents":[
+    {"type":"directory","name":"app","contents":[
+      {"type":"directory","name":"lib","contents":[
+        {"type":"file","name":"core.jar","compressed":11}
+      ]}
+    ]}
+  ]}
+`},
+}
+
+func TestFS(t *testing.T) {
+	r := archive(t)
+	fs, err := New(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.Root = "app.jar"
+	for _, test := range tests {
+		out := new(bytes.Buffer)
+		test.opts.Fs = fs
+		test.opts.OutFile = out
+		inf := tree.New(fs.Root)
+		inf.Visit(test.opts)
+		inf.Print(test.opts)
+		if actual := out.String(); actual != test.expected {
+			t.Errorf("%s:\nactual\n%s\n != expect\n%s\n", test.name, actual, test.expected)
+		}
+	}
+}
+
+func TestFSEntries(t *testing.T) {
+	r := archive(t)
+	fs, err := New(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != os.ModeDir|0750 {
+		t.Errorf("app: got mode %v, expected %v", fi.Mode(), os.ModeDir|0750)
+	}
+	if fi, _ := fs.Stat("app/bin"); fi == nil || !fi.IsDir() {
+		t.Errorf("app/bin: expected an implied directory, got %v", fi)
+	}
+	if _, ok := fi.Sys().(*Header); !ok {
+		t.Errorf("expected the zip header in Sys, got %#v", fi.Sys())
+	}
+	// Both sizes of the files are known.
+	if fi, err = fs.Stat("app/lib/core.jar"); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := fi.Sys().(tree.PackedSizer); !ok || fi.Size() != 1000 || c.PackedSize() != 11 {
+		t.Errorf("core.jar: got size %d, and Sys %#v", fi.Size(), fi.Sys())
+	}
+	// The fields of the zip header are not shadowed.
+	if h := fi.Sys().(*Header); uint64(h.CompressedSize) != h.CompressedSize64 {
+		t.Errorf("core.jar: got compressed sizes %d and %d", h.CompressedSize, h.CompressedSize64)
+	}
+	target, resolved, err := fs.Readlink("app/lib/latest.jar")
+	if err != nil || target != "core.jar" || resolved != filepath.FromSlash("app/lib/core.jar") {
+		t.Errorf("readlink: got %q, %q, %v", target, resolved, err)
+	}
+	rc, err := fs.Open("app/bin/run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); string(b) != "#!/bin/sh\n" {
+		t.Errorf("open: got %q", b)
+	}
+	if _, err := fs.Stat("app/missing"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}